@@ -1,28 +1,549 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
+	"math"
+	"math/rand"
 	"os"
 	"os/signal"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
+	"gopkg.in/yaml.v3"
 )
 
+// Latency histogram bounds: 10 microseconds (fast point queries) to 60
+// seconds (worst-case stalls). Resolution (significant decimal digits) is
+// configurable via TestConfig.LatencyBuckets / -latency-buckets, since
+// coarser buckets are cheaper to merge across many clients at high
+// concurrency.
+const (
+	latencyHistMinMicros = 10.0
+	latencyHistMaxMicros = 60 * 1000000.0
+)
+
+// LatencyHistogram is an additive, fixed-size log-linear (HDR-style)
+// histogram of query latencies. Because buckets are additive, per-client and
+// per-second-window histograms can be merged with a simple bucket-wise sum.
+// Min/max/mean are tracked exactly alongside the buckets, since that's free
+// to do on Record and more precise than deriving them from bucket edges.
+type LatencyHistogram struct {
+	sigDigits int
+	factor    float64
+	counts    []uint64
+
+	count int64
+	sum   time.Duration
+	min   time.Duration
+	max   time.Duration
+}
+
+// newLatencyHistogram creates an empty histogram with the given resolution
+// (significant decimal digits, e.g. 2 or 3).
+func newLatencyHistogram(sigDigits int) *LatencyHistogram {
+	factor := math.Pow(10, 1.0/math.Pow(10, float64(sigDigits)))
+	bucketCount := int(math.Ceil(math.Log(latencyHistMaxMicros/latencyHistMinMicros)/math.Log(factor))) + 1
+	return &LatencyHistogram{
+		sigDigits: sigDigits,
+		factor:    factor,
+		counts:    make([]uint64, bucketCount),
+	}
+}
+
+func (h *LatencyHistogram) bucketIndex(d time.Duration) int {
+	micros := float64(d.Microseconds())
+	if micros < latencyHistMinMicros {
+		micros = latencyHistMinMicros
+	}
+	if micros > latencyHistMaxMicros {
+		micros = latencyHistMaxMicros
+	}
+	idx := int(math.Log(micros/latencyHistMinMicros) / math.Log(h.factor))
+	if idx >= len(h.counts) {
+		idx = len(h.counts) - 1
+	}
+	return idx
+}
+
+func (h *LatencyHistogram) bucketUpperMicros(idx int) float64 {
+	return latencyHistMinMicros * math.Pow(h.factor, float64(idx+1))
+}
+
+// Record adds a single observed latency to the histogram.
+func (h *LatencyHistogram) Record(d time.Duration) {
+	h.counts[h.bucketIndex(d)]++
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.sum += d
+	h.count++
+}
+
+// Merge bucket-wise adds another histogram's counts into this one.
+func (h *LatencyHistogram) Merge(other *LatencyHistogram) {
+	if other == nil || other.count == 0 {
+		return
+	}
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+	if h.count == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+	h.sum += other.sum
+	h.count += other.count
+}
+
+// Total returns the number of samples recorded.
+func (h *LatencyHistogram) Total() uint64 {
+	var total uint64
+	for _, c := range h.counts {
+		total += c
+	}
+	return total
+}
+
+// Percentile returns the latency at percentile p (0-100).
+func (h *LatencyHistogram) Percentile(p float64) time.Duration {
+	total := h.Total()
+	if total == 0 {
+		return 0
+	}
+	target := uint64(math.Ceil(p / 100 * float64(total)))
+	if target == 0 {
+		target = 1
+	}
+	var cumulative uint64
+	for i, c := range h.counts {
+		cumulative += c
+		if cumulative >= target {
+			return time.Duration(h.bucketUpperMicros(i) * float64(time.Microsecond))
+		}
+	}
+	return time.Duration(h.bucketUpperMicros(len(h.counts)-1) * float64(time.Microsecond))
+}
+
+// Min returns the exact minimum recorded latency.
+func (h *LatencyHistogram) Min() time.Duration {
+	return h.min
+}
+
+// Max returns the exact maximum recorded latency.
+func (h *LatencyHistogram) Max() time.Duration {
+	return h.max
+}
+
+// Mean returns the exact average recorded latency.
+func (h *LatencyHistogram) Mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.count)
+}
+
+// HistogramBucket is a sparse (non-empty-only) JSON representation of a
+// LatencyHistogram, keyed by the bucket's upper bound in milliseconds.
+type HistogramBucket struct {
+	LeMs  float64 `json:"le_ms"`
+	Count uint64  `json:"count"`
+}
+
+// Buckets returns the non-empty buckets for JSON export, so downstream
+// tooling can recompute arbitrary quantiles.
+func (h *LatencyHistogram) Buckets() []HistogramBucket {
+	var out []HistogramBucket
+	for i, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		out = append(out, HistogramBucket{LeMs: h.bucketUpperMicros(i) / 1000.0, Count: c})
+	}
+	return out
+}
+
+// TimeSeriesPoint summarizes one 1-second window of the per-second latency
+// time series, so a warmup ramp or a latency drift during the run is visible
+// instead of being averaged away by a single aggregate.
+type TimeSeriesPoint struct {
+	Second int     `json:"second"`
+	Count  int     `json:"count"`
+	MinMs  float64 `json:"min_ms"`
+	AvgMs  float64 `json:"avg_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+}
+
+// durationMs converts a time.Duration to fractional milliseconds for JSON/report output.
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Workload generates the queries a client issues during a test run. The
+// builtin workload (used when -workload-file is not set) reproduces this
+// tool's original fixed-future-timestamp COUNT(*) query; a --workload-file
+// spec swaps in multiple weighted query templates instead, so that e.g.
+// stale, future, and current reads can be mixed into one run and compared
+// via TestResult's per-kind breakdown.
+type Workload interface {
+	// Next returns the query to run next, its placeholder arguments (nil
+	// if the query takes none), and a short label ("kind") used to
+	// bucket this query's results separately in TestResult.
+	Next(rng *rand.Rand) (query string, args []any, kind string)
+}
+
+// builtinWorkload reproduces the tool's original hardcoded query: a
+// COUNT(*) as of a fixed future timestamp offset from now.
+type builtinWorkload struct {
+	tableName      string
+	futureTSMicros int64
+}
+
+func (w *builtinWorkload) Next(rng *rand.Rand) (string, []any, string) {
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s AS OF TIMESTAMP NOW() + INTERVAL %d MICROSECOND",
+		w.tableName, w.futureTSMicros)
+	return query, nil, "future_ts"
+}
+
+// WorkloadTemplate describes one query shape in a --workload-file spec.
+// SQL is a query string containing exactly one "%s" placeholder for the AS
+// OF clause (filled in from StalenessMs) and, if ParamMax > ParamMin, a "?"
+// placeholder bound to a generated int64 parameter drawn per Distribution.
+//
+// StalenessMs controls the AS OF clause: positive reads as of that many
+// milliseconds in the past, negative reads as of that many milliseconds in
+// the future, and zero omits AS OF entirely (a plain current read).
+type WorkloadTemplate struct {
+	Kind         string  `yaml:"kind"`
+	Weight       float64 `yaml:"weight"`
+	SQL          string  `yaml:"sql"`
+	Distribution string  `yaml:"distribution"` // "uniform" (default), "zipfian", or "hotspot"
+	ParamMin     int64   `yaml:"param_min"`
+	ParamMax     int64   `yaml:"param_max"`
+
+	// ZipfianTheta is the skew parameter for Distribution "zipfian" (0 <
+	// theta < 1; higher is more skewed towards ParamMin). Defaults to 0.99.
+	ZipfianTheta float64 `yaml:"zipfian_theta"`
+
+	// HotspotFraction/HotspotWeight configure Distribution "hotspot":
+	// HotspotWeight of traffic (default 0.8) lands in the first
+	// HotspotFraction (default 0.02) of the parameter range, the rest is
+	// uniform across the full range.
+	HotspotFraction float64 `yaml:"hotspot_fraction"`
+	HotspotWeight   float64 `yaml:"hotspot_weight"`
+
+	StalenessMs int64 `yaml:"staleness_ms"`
+}
+
+// WorkloadSpec is the top-level shape of a --workload-file.
+type WorkloadSpec struct {
+	Templates []WorkloadTemplate `yaml:"templates"`
+}
+
+// asOfClause renders the "AS OF TIMESTAMP ..." fragment for a template's
+// staleness offset, or "" for a plain current read.
+func asOfClause(stalenessMs int64) string {
+	switch {
+	case stalenessMs > 0:
+		return fmt.Sprintf("AS OF TIMESTAMP NOW() - INTERVAL %d MICROSECOND", stalenessMs*1000)
+	case stalenessMs < 0:
+		return fmt.Sprintf("AS OF TIMESTAMP NOW() + INTERVAL %d MICROSECOND", -stalenessMs*1000)
+	default:
+		return ""
+	}
+}
+
+// zipfianGenerator draws integers in the half-open range 0 to n from an approximate Zipfian
+// distribution using the closed-form inverse-transform approximation
+// popularized by YCSB's ZipfianGenerator (itself from Gray et al.,
+// "Quickly Generating Billion-Record Synthetic Databases"). Unlike
+// math/rand.Zipf, it needs no per-draw state tied to a single rand.Source,
+// so one generator can safely be shared and driven by whatever *rand.Rand
+// is passed to Next.
+type zipfianGenerator struct {
+	n     int64
+	theta float64
+	alpha float64
+	zetaN float64
+	eta   float64
+}
+
+func zeta(n int64, theta float64) float64 {
+	var sum float64
+	for i := int64(1); i <= n; i++ {
+		sum += 1.0 / math.Pow(float64(i), theta)
+	}
+	return sum
+}
+
+func newZipfianGenerator(n int64, theta float64) *zipfianGenerator {
+	if n < 2 {
+		n = 2
+	}
+	zetaN := zeta(n, theta)
+	zeta2 := zeta(2, theta)
+	return &zipfianGenerator{
+		n:     n,
+		theta: theta,
+		alpha: 1.0 / (1.0 - theta),
+		zetaN: zetaN,
+		eta:   (1 - math.Pow(2.0/float64(n), 1-theta)) / (1 - zeta2/zetaN),
+	}
+}
+
+func (z *zipfianGenerator) next(rng *rand.Rand) int64 {
+	u := rng.Float64()
+	uz := u * z.zetaN
+	if uz < 1.0 {
+		return 0
+	}
+	if uz < 1.0+math.Pow(0.5, z.theta) {
+		return 1
+	}
+	v := int64(float64(z.n) * math.Pow(z.eta*u-z.eta+1, z.alpha))
+	if v >= z.n {
+		v = z.n - 1
+	}
+	return v
+}
+
+// paramFunc draws one int64 parameter value per call, per a template's
+// configured Distribution.
+type paramFunc func(rng *rand.Rand) int64
+
+func newParamFunc(t WorkloadTemplate) paramFunc {
+	lo, hi := t.ParamMin, t.ParamMax
+	if hi < lo {
+		hi = lo
+	}
+	span := hi - lo + 1
+
+	switch t.Distribution {
+	case "zipfian":
+		theta := t.ZipfianTheta
+		if theta <= 0 || theta >= 1 {
+			theta = 0.99
+		}
+		z := newZipfianGenerator(span, theta)
+		return func(rng *rand.Rand) int64 {
+			return lo + z.next(rng)
+		}
+	case "hotspot":
+		fraction := t.HotspotFraction
+		if fraction <= 0 {
+			fraction = 0.02
+		}
+		weight := t.HotspotWeight
+		if weight <= 0 {
+			weight = 0.8
+		}
+		hotSpan := int64(float64(span) * fraction)
+		if hotSpan < 1 {
+			hotSpan = 1
+		}
+		return func(rng *rand.Rand) int64 {
+			if rng.Float64() < weight {
+				return lo + rng.Int63n(hotSpan)
+			}
+			return lo + rng.Int63n(span)
+		}
+	default: // "uniform"
+		return func(rng *rand.Rand) int64 {
+			return lo + rng.Int63n(span)
+		}
+	}
+}
+
+// compiledTemplate is a WorkloadTemplate with its parameter generator
+// pre-built, so Next doesn't re-parse the distribution on every call.
+type compiledTemplate struct {
+	kind        string
+	sqlFmt      string
+	staleness   int64
+	cumWeight   float64
+	hasParam    bool
+	genParam    paramFunc
+}
+
+// weightedWorkload picks a template per call proportional to its Weight,
+// then formats that template's query with its AS OF clause and a generated
+// parameter (if any).
+type weightedWorkload struct {
+	templates []compiledTemplate
+	total     float64
+}
+
+func newWeightedWorkload(spec WorkloadSpec) (*weightedWorkload, error) {
+	if len(spec.Templates) == 0 {
+		return nil, fmt.Errorf("workload spec has no templates")
+	}
+	w := &weightedWorkload{}
+	var cum float64
+	for _, t := range spec.Templates {
+		if t.Kind == "" {
+			return nil, fmt.Errorf("workload template missing kind")
+		}
+		if t.Weight <= 0 {
+			return nil, fmt.Errorf("workload template %q: weight must be > 0", t.Kind)
+		}
+		cum += t.Weight
+		w.templates = append(w.templates, compiledTemplate{
+			kind:      t.Kind,
+			sqlFmt:    t.SQL,
+			staleness: t.StalenessMs,
+			cumWeight: cum,
+			hasParam:  t.ParamMax > t.ParamMin,
+			genParam:  newParamFunc(t),
+		})
+	}
+	w.total = cum
+	return w, nil
+}
+
+func (w *weightedWorkload) pick(rng *rand.Rand) compiledTemplate {
+	target := rng.Float64() * w.total
+	for _, t := range w.templates {
+		if target < t.cumWeight {
+			return t
+		}
+	}
+	return w.templates[len(w.templates)-1]
+}
+
+func (w *weightedWorkload) Next(rng *rand.Rand) (string, []any, string) {
+	t := w.pick(rng)
+	query := fmt.Sprintf(t.sqlFmt, asOfClause(t.staleness))
+	if !t.hasParam {
+		return query, nil, t.kind
+	}
+	return query, []any{t.genParam(rng)}, t.kind
+}
+
+// loadWorkloadFile parses a --workload-file spec. YAML syntax is a
+// superset of JSON, so both gopkg.in/yaml.v3 and encoding/json files parse
+// through the same path.
+func loadWorkloadFile(path string) (Workload, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading workload file: %w", err)
+	}
+	var spec WorkloadSpec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("parsing workload file: %w", err)
+	}
+	return newWeightedWorkload(spec)
+}
+
+// WorkloadKindResult aggregates attempts, errors, and latency for one
+// workload template ("kind"), so results from templates sharing a run
+// (e.g. stale vs. future vs. current reads) can be compared side by side.
+type WorkloadKindResult struct {
+	Attempts int     `json:"attempts"`
+	Errors   int     `json:"errors"`
+	MinMs    float64 `json:"latency_min_ms"`
+	MeanMs   float64 `json:"latency_mean_ms"`
+	P50Ms    float64 `json:"latency_p50_ms"`
+	P95Ms    float64 `json:"latency_p95_ms"`
+	P99Ms    float64 `json:"latency_p99_ms"`
+	MaxMs    float64 `json:"latency_max_ms"`
+}
+
+// buildWorkloadResults assembles the per-kind breakdown of a completed
+// RunTest from the attempt/error counts and successful-query latency
+// histograms merged across all clients.
+func buildWorkloadResults(attempts, errs map[string]int, latencies map[string]*LatencyHistogram) map[string]WorkloadKindResult {
+	if len(attempts) == 0 {
+		return nil
+	}
+	results := make(map[string]WorkloadKindResult, len(attempts))
+	for kind, n := range attempts {
+		h := latencies[kind]
+		if h == nil {
+			h = newLatencyHistogram(2)
+		}
+		results[kind] = WorkloadKindResult{
+			Attempts: n,
+			Errors:   errs[kind],
+			MinMs:    durationMs(h.Min()),
+			MeanMs:   durationMs(h.Mean()),
+			P50Ms:    durationMs(h.Percentile(50)),
+			P95Ms:    durationMs(h.Percentile(95)),
+			P99Ms:    durationMs(h.Percentile(99)),
+			MaxMs:    durationMs(h.Max()),
+		}
+	}
+	return results
+}
+
+// EndpointResult aggregates one TiDB endpoint's attempts, errors, and
+// latency for a test run, so skew across endpoints behind a load balancer
+// (e.g. one node returning future-ts errors far more often) is visible
+// without running the tool once per endpoint.
+type EndpointResult struct {
+	Attempts  int     `json:"attempts"`
+	Errors    int     `json:"errors"`
+	QPS       float64 `json:"qps"`
+	ErrorRate float64 `json:"error_rate"`
+	P99Ms     float64 `json:"latency_p99_ms"`
+}
+
+// buildEndpointResults assembles the per-endpoint breakdown of a completed
+// RunTest from attempt/error/success counts and successful-query latency
+// histograms merged per endpoint across all clients.
+func buildEndpointResults(attempts, errs, queries map[string]int, latencies map[string]*LatencyHistogram, actualDuration float64) map[string]EndpointResult {
+	if len(attempts) < 2 {
+		// A single endpoint has nothing to compare against; the
+		// aggregate table already covers it.
+		return nil
+	}
+	results := make(map[string]EndpointResult, len(attempts))
+	for endpoint, n := range attempts {
+		h := latencies[endpoint]
+		if h == nil {
+			h = newLatencyHistogram(2)
+		}
+		errRate := 0.0
+		if n > 0 {
+			errRate = float64(errs[endpoint]) / float64(n)
+		}
+		results[endpoint] = EndpointResult{
+			Attempts:  n,
+			Errors:    errs[endpoint],
+			QPS:       float64(queries[endpoint]) / actualDuration,
+			ErrorRate: errRate,
+			P99Ms:     durationMs(h.Percentile(99)),
+		}
+	}
+	return results
+}
+
 // TestConfig holds all configuration parameters for the test
 type TestConfig struct {
-	Host              string
-	Port              int
-	User              string
+	// Endpoints is the list of "host:port" TiDB servers clients connect
+	// to, populated either from --endpoints or from the --host/--port
+	// shorthand (a single endpoint). EndpointStrategy picks which
+	// endpoint a given client connection uses: "round-robin" (default)
+	// or "random".
+	Endpoints        []string
+	EndpointStrategy string
+	User             string
 	Password          string
 	Database          string
 	TableName         string
@@ -33,6 +554,26 @@ type TestConfig struct {
 	FutureTS          int
 	Cooldown          int
 	Verbose           bool
+	LatencyBuckets    int // significant decimal digits of histogram resolution
+
+	// Adaptive error-based throttling: once a client's rolling error rate
+	// exceeds ThrottleErrorRate, it backs off instead of retrying at full
+	// speed. ThrottleDisable restores the old unconditional 100ms sleep per
+	// error.
+	ThrottleErrorRate        float64
+	ThrottleMaxDelay         time.Duration
+	ThrottleRecoveryHalfLife time.Duration
+	ThrottleDisable          bool
+
+	// Workload generates the query stream each client runs. Built from
+	// --workload-file if set, or a builtinWorkload reproducing the
+	// original fixed-future-timestamp query otherwise.
+	Workload Workload
+
+	// Resume loads completed concurrency levels from checkpointFilePath
+	// (if its config hash matches this config) into TestResults and skips
+	// them in RunAllTests, instead of rerunning the whole matrix.
+	Resume bool
 }
 
 // TestResult holds the results of a single test run at a specific concurrency level
@@ -47,13 +588,164 @@ type TestResult struct {
 	SuccessfulQPS       float64 `json:"successful_qps"`
 	RecordsPerSec       float64 `json:"records_per_sec"`
 	ErrorRate           float64 `json:"error_rate"`
+
+	LatencyMinMs  float64 `json:"latency_min_ms"`
+	LatencyMeanMs float64 `json:"latency_mean_ms"`
+	LatencyP50Ms  float64 `json:"latency_p50_ms"`
+	LatencyP95Ms  float64 `json:"latency_p95_ms"`
+	LatencyP99Ms  float64 `json:"latency_p99_ms"`
+	LatencyP999Ms float64 `json:"latency_p999_ms"`
+	LatencyMaxMs  float64 `json:"latency_max_ms"`
+
+	ErrorLatencyMinMs  float64 `json:"error_latency_min_ms"`
+	ErrorLatencyMeanMs float64 `json:"error_latency_mean_ms"`
+	ErrorLatencyP50Ms  float64 `json:"error_latency_p50_ms"`
+	ErrorLatencyP95Ms  float64 `json:"error_latency_p95_ms"`
+	ErrorLatencyP99Ms  float64 `json:"error_latency_p99_ms"`
+	ErrorLatencyMaxMs  float64 `json:"error_latency_max_ms"`
+
+	LatencyBucketCounts      []HistogramBucket `json:"latency_bucket_counts,omitempty"`
+	ErrorLatencyBucketCounts []HistogramBucket `json:"error_latency_bucket_counts,omitempty"`
+	LatencyTimeSeries        []TimeSeriesPoint `json:"latency_time_series,omitempty"`
+
+	ThrottledDurationSec float64 `json:"throttled_duration_sec"`
+	ThrottleEvents       int     `json:"throttle_events"`
+
+	// WorkloadResults breaks attempts/errors/latency down by workload
+	// template "kind", so a run mixing e.g. stale, future, and current
+	// reads can compare them side by side. Keyed by WorkloadTemplate.Kind
+	// ("future_ts" for the builtin workload).
+	WorkloadResults map[string]WorkloadKindResult `json:"workload_results,omitempty"`
+
+	// EndpointResults breaks attempts/errors/QPS/latency down by the
+	// "host:port" endpoint a client connected to, populated only when
+	// more than one endpoint is in use.
+	EndpointResults map[string]EndpointResult `json:"endpoint_results,omitempty"`
+
+	// Partial marks a result checkpointed mid-run because SIGINT arrived
+	// before the concurrency level finished its full Duration. RunAllTests
+	// retries partial levels on the next --resume run instead of skipping
+	// them.
+	Partial bool `json:"partial,omitempty"`
 }
 
 // ClientResult holds the results from a single test client
 type ClientResult struct {
-	Queries        int
-	Errors         int
-	RecordsScanned int
+	Queries          int
+	Errors           int
+	RecordsScanned   int
+	Latencies        *LatencyHistogram   // successful queries only
+	ErrorLatencies   *LatencyHistogram   // failed attempts, timed until the error return
+	Windows          []*LatencyHistogram // successful-query latencies bucketed by 1s window since test start
+	ThrottledDuration time.Duration      // time this client spent sleeping off an error-rate backoff
+	ThrottleEvents    int                // number of times this client newly entered a backoff
+
+	KindAttempts  map[string]int
+	KindErrors    map[string]int
+	KindLatencies map[string]*LatencyHistogram // successful queries only, per workload kind
+
+	Endpoint string // "host:port" this client connected to
+}
+
+// throttleWindowSize is the number of recent query outcomes tracked by
+// errorThrottler's rolling window, used to estimate the current error rate.
+const throttleWindowSize = 200
+
+// errorThrottler backs a client off once its rolling error rate exceeds a
+// threshold, instead of sleeping a fixed duration on every error. The delay
+// grows exponentially while errors keep breaching the threshold, and decays
+// back toward zero on a half-life once the error rate recovers, so a client
+// ramps down slowly if errors are still happening but recovers quickly once
+// they stop.
+type errorThrottler struct {
+	errorRate    float64
+	maxDelay     time.Duration
+	recoveryHalf time.Duration
+	disabled     bool
+
+	outcomes [throttleWindowSize]bool
+	filled   int
+	pos      int
+	errors   int
+
+	delay          time.Duration
+	lastDecay      time.Time
+	throttleEvents int
+}
+
+func newErrorThrottler(cfg TestConfig) *errorThrottler {
+	return &errorThrottler{
+		errorRate:    cfg.ThrottleErrorRate,
+		maxDelay:     cfg.ThrottleMaxDelay,
+		recoveryHalf: cfg.ThrottleRecoveryHalfLife,
+		disabled:     cfg.ThrottleDisable,
+		lastDecay:    time.Now(),
+	}
+}
+
+// record adds a query outcome to the rolling window.
+func (e *errorThrottler) record(isError bool) {
+	if e.filled == throttleWindowSize && e.outcomes[e.pos] {
+		e.errors--
+	}
+	e.outcomes[e.pos] = isError
+	if isError {
+		e.errors++
+	}
+	e.pos = (e.pos + 1) % throttleWindowSize
+	if e.filled < throttleWindowSize {
+		e.filled++
+	}
+}
+
+// rate returns the current rolling error rate.
+func (e *errorThrottler) rate() float64 {
+	if e.filled == 0 {
+		return 0
+	}
+	return float64(e.errors) / float64(e.filled)
+}
+
+// decay relaxes the backoff delay toward zero based on wall-clock time
+// elapsed since the last decay, at the configured half-life.
+func (e *errorThrottler) decay() {
+	now := time.Now()
+	elapsed := now.Sub(e.lastDecay)
+	e.lastDecay = now
+	if e.delay == 0 || e.recoveryHalf <= 0 {
+		return
+	}
+	halfLives := float64(elapsed) / float64(e.recoveryHalf)
+	e.delay = time.Duration(float64(e.delay) * math.Pow(0.5, halfLives))
+	if e.delay < time.Millisecond {
+		e.delay = 0
+	}
+}
+
+// afterAttempt updates the rolling window with the outcome of a query and,
+// if the rolling error rate breaches the threshold, grows the backoff delay
+// (doubling, capped at maxDelay) and returns how long to sleep before the
+// next attempt. When the error rate is within bounds, the delay instead
+// decays toward zero.
+func (e *errorThrottler) afterAttempt(isError bool) time.Duration {
+	e.record(isError)
+	if e.errorRate <= 0 {
+		return 0
+	}
+	if e.rate() > e.errorRate {
+		if e.delay == 0 {
+			e.delay = 50 * time.Millisecond
+			e.throttleEvents++
+		} else {
+			e.delay *= 2
+		}
+		if e.delay > e.maxDelay {
+			e.delay = e.maxDelay
+		}
+	} else {
+		e.decay()
+	}
+	return e.delay
 }
 
 // TestRunner orchestrates the entire test process
@@ -61,6 +753,28 @@ type TestRunner struct {
 	Config      TestConfig
 	DB          *sql.DB
 	TestResults map[int]TestResult
+
+	nextEndpoint uint64 // atomic round-robin counter, see pickEndpoint
+}
+
+// pickEndpoint selects which TiDB endpoint a new client connection should
+// use, per Config.EndpointStrategy ("round-robin", the default, or
+// "random").
+func (t *TestRunner) pickEndpoint(rng *rand.Rand) string {
+	endpoints := t.Config.Endpoints
+	if len(endpoints) == 1 {
+		return endpoints[0]
+	}
+	if t.Config.EndpointStrategy == "random" {
+		return endpoints[rng.Intn(len(endpoints))]
+	}
+	idx := atomic.AddUint64(&t.nextEndpoint, 1) - 1
+	return endpoints[idx%uint64(len(endpoints))]
+}
+
+// buildDSN formats a MySQL DSN for one endpoint ("host:port").
+func buildDSN(user, password, endpoint, database, timeout string) string {
+	return fmt.Sprintf("%s:%s@tcp(%s)/%s?timeout=%s&autocommit=true", user, password, endpoint, database, timeout)
 }
 
 // NewTestRunner creates a new test runner with the given configuration
@@ -73,8 +787,7 @@ func NewTestRunner(config TestConfig) *TestRunner {
 
 // Connect establishes a connection to the TiDB database
 func (t *TestRunner) Connect() error {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?timeout=10s&autocommit=true",
-		t.Config.User, t.Config.Password, t.Config.Host, t.Config.Port, t.Config.Database)
+	dsn := buildDSN(t.Config.User, t.Config.Password, t.Config.Endpoints[0], t.Config.Database, "10s")
 
 	var err error
 	t.DB, err = sql.Open("mysql", dsn)
@@ -93,7 +806,11 @@ func (t *TestRunner) Connect() error {
 		return err
 	}
 
-	fmt.Printf("Connected to TiDB: %s:%d\n", t.Config.Host, t.Config.Port)
+	fmt.Printf("Connected to TiDB: %s\n", t.Config.Endpoints[0])
+	if len(t.Config.Endpoints) > 1 {
+		fmt.Printf("Clients will be distributed across %d endpoints (%s): %s\n",
+			len(t.Config.Endpoints), t.Config.EndpointStrategy, strings.Join(t.Config.Endpoints, ", "))
+	}
 	return nil
 }
 
@@ -183,8 +900,7 @@ func (t *TestRunner) SplitTable() error {
 	fmt.Printf("Splitting table into %d regions...\n", t.Config.RegionCount)
 
 	// Use a separate connection for SPLIT operations
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?timeout=300s&autocommit=true",
-		t.Config.User, t.Config.Password, t.Config.Host, t.Config.Port, t.Config.Database)
+	dsn := buildDSN(t.Config.User, t.Config.Password, t.Config.Endpoints[0], t.Config.Database, "300s")
 
 	splitDB, err := sql.Open("mysql", dsn)
 	if err != nil {
@@ -217,19 +933,27 @@ func (t *TestRunner) SplitTable() error {
 }
 
 // RunClient executes the test queries for a single client
-func (t *TestRunner) RunClient(clientID int, duration time.Duration, resultChan chan<- ClientResult) {
+func (t *TestRunner) RunClient(ctx context.Context, clientID int, duration time.Duration, resultChan chan<- ClientResult) {
 	// Create a new connection for this client
 	if t.Config.Verbose {
 		fmt.Printf("Client %d: Connecting to TiDB...\n", clientID)
 	}
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?timeout=300s&autocommit=true",
-		t.Config.User, t.Config.Password, t.Config.Host, t.Config.Port, t.Config.Database)
+	rng := rand.New(rand.NewSource(time.Now().UnixNano() + int64(clientID)))
+	endpoint := t.pickEndpoint(rng)
+	dsn := buildDSN(t.Config.User, t.Config.Password, endpoint, t.Config.Database, "300s")
 
 	conn, err := sql.Open("mysql", dsn)
 	if err != nil {
-		fmt.Printf("Client %d: Failed to connect: %v\n", clientID, err)
-		resultChan <- ClientResult{0, 1, 0}
+		fmt.Printf("Client %d: Failed to connect to %s: %v\n", clientID, endpoint, err)
+		resultChan <- ClientResult{
+			Queries:        0,
+			Errors:         1,
+			RecordsScanned: 0,
+			Latencies:      newLatencyHistogram(t.Config.LatencyBuckets),
+			ErrorLatencies: newLatencyHistogram(t.Config.LatencyBuckets),
+			Endpoint:       endpoint,
+		}
 		return
 	}
 	defer conn.Close()
@@ -238,8 +962,15 @@ func (t *TestRunner) RunClient(clientID int, duration time.Duration, resultChan
 	var count int
 	err = conn.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s LIMIT 1", t.Config.TableName)).Scan(&count)
 	if err != nil {
-		fmt.Printf("Client %d: ERROR - Cannot access test table: %v\n", clientID, err)
-		resultChan <- ClientResult{0, 1, 0}
+		fmt.Printf("Client %d: ERROR - Cannot access test table on %s: %v\n", clientID, endpoint, err)
+		resultChan <- ClientResult{
+			Queries:        0,
+			Errors:         1,
+			RecordsScanned: 0,
+			Latencies:      newLatencyHistogram(t.Config.LatencyBuckets),
+			ErrorLatencies: newLatencyHistogram(t.Config.LatencyBuckets),
+			Endpoint:       endpoint,
+		}
 		return
 	}
 
@@ -255,28 +986,78 @@ func (t *TestRunner) RunClient(clientID int, duration time.Duration, resultChan
 	errorCount := 0
 	recordsScanned := 0
 
-	for time.Now().Before(endTime) {
-		// Use fixed future timestamp
-		microseconds := t.Config.FutureTS * 1000
-		futureTS := fmt.Sprintf("NOW() + INTERVAL %d MICROSECOND", microseconds)
-		query := fmt.Sprintf("SELECT COUNT(*) FROM %s AS OF TIMESTAMP %s", t.Config.TableName, futureTS)
+	latencies := newLatencyHistogram(t.Config.LatencyBuckets)
+	errorLatencies := newLatencyHistogram(t.Config.LatencyBuckets)
+
+	// windows buckets successful-query latencies by the 1s window (relative
+	// to startTime) they fell in, so RunTest can merge a per-second time
+	// series across clients and show drift/warmup during the run.
+	windows := make([]*LatencyHistogram, int(duration.Seconds())+2)
+
+	throttler := newErrorThrottler(t.Config)
+	var throttledDuration time.Duration
+
+	workload := t.Config.Workload
+	if workload == nil {
+		workload = &builtinWorkload{tableName: t.Config.TableName, futureTSMicros: int64(t.Config.FutureTS) * 1000}
+	}
+
+	kindAttempts := make(map[string]int)
+	kindErrors := make(map[string]int)
+	kindLatencies := make(map[string]*LatencyHistogram)
+
+	for ctx.Err() == nil && time.Now().Before(endTime) {
+		query, args, kind := workload.Next(rng)
+		kindAttempts[kind]++
 
 		var recordCount int
-		err := conn.QueryRow(query).Scan(&recordCount)
+		queryStart := time.Now()
+		var err error
+		if len(args) > 0 {
+			err = conn.QueryRow(query, args...).Scan(&recordCount)
+		} else {
+			err = conn.QueryRow(query).Scan(&recordCount)
+		}
+		elapsed := time.Since(queryStart)
 		if err != nil {
 			errorCount++
+			kindErrors[kind]++
+			errorLatencies.Record(elapsed)
 			if t.Config.Verbose && (errorCount == 1 || (errorCount <= 10 && errorCount%5 == 0) || errorCount%100 == 0) {
 				fmt.Printf("Client %d: Query failed (%d times): %v...\n", clientID, errorCount, err)
 			} else if !t.Config.Verbose && (errorCount == 1 || errorCount%500 == 0) {
 				fmt.Printf("Client %d: Query failed (%d times): %v...\n", clientID, errorCount, err)
 			}
 
-			time.Sleep(100 * time.Millisecond) // Avoid immediate retry
+			if t.Config.ThrottleDisable {
+				time.Sleep(100 * time.Millisecond) // Avoid immediate retry
+			} else if delay := throttler.afterAttempt(true); delay > 0 {
+				throttledDuration += delay
+				time.Sleep(delay)
+			}
 			continue
 		}
 
+		if !t.Config.ThrottleDisable {
+			throttler.afterAttempt(false)
+		}
+
 		queryCount++
 		recordsScanned += recordCount
+		latencies.Record(elapsed)
+		if kindLatencies[kind] == nil {
+			kindLatencies[kind] = newLatencyHistogram(t.Config.LatencyBuckets)
+		}
+		kindLatencies[kind].Record(elapsed)
+
+		window := int(time.Since(startTime).Seconds())
+		if window >= len(windows) {
+			window = len(windows) - 1
+		}
+		if windows[window] == nil {
+			windows[window] = newLatencyHistogram(t.Config.LatencyBuckets)
+		}
+		windows[window].Record(elapsed)
 
 		// Progress reporting
 		if t.Config.Verbose {
@@ -295,11 +1076,24 @@ func (t *TestRunner) RunClient(clientID int, duration time.Duration, resultChan
 	fmt.Printf("Client %d completed: %d queries, %d errors, %d records\n",
 		clientID, queryCount, errorCount, recordsScanned)
 
-	resultChan <- ClientResult{queryCount, errorCount, recordsScanned}
+	resultChan <- ClientResult{
+		Queries:           queryCount,
+		Errors:            errorCount,
+		RecordsScanned:    recordsScanned,
+		Latencies:         latencies,
+		ErrorLatencies:    errorLatencies,
+		Windows:           windows,
+		ThrottledDuration: throttledDuration,
+		ThrottleEvents:    throttler.throttleEvents,
+		KindAttempts:      kindAttempts,
+		KindErrors:        kindErrors,
+		KindLatencies:     kindLatencies,
+		Endpoint:          endpoint,
+	}
 }
 
 // RunTest runs a test with a specific concurrency level
-func (t *TestRunner) RunTest(concurrency int) TestResult {
+func (t *TestRunner) RunTest(ctx context.Context, concurrency int) TestResult {
 	fmt.Printf("\nStarting test with concurrency %d...\n", concurrency)
 
 	resultChan := make(chan ClientResult, concurrency)
@@ -312,7 +1106,7 @@ func (t *TestRunner) RunTest(concurrency int) TestResult {
 		wg.Add(1)
 		go func(clientID int) {
 			defer wg.Done()
-			t.RunClient(clientID, time.Duration(t.Config.Duration)*time.Second, resultChan)
+			t.RunClient(ctx, clientID, time.Duration(t.Config.Duration)*time.Second, resultChan)
 		}(i)
 
 		// Progress for large concurrency values
@@ -332,10 +1126,67 @@ func (t *TestRunner) RunTest(concurrency int) TestResult {
 	totalErrors := 0
 	totalRecords := 0
 
+	latencies := newLatencyHistogram(t.Config.LatencyBuckets)
+	errorLatencies := newLatencyHistogram(t.Config.LatencyBuckets)
+	var windows []*LatencyHistogram
+	var throttledDuration time.Duration
+	throttleEvents := 0
+
+	kindAttempts := make(map[string]int)
+	kindErrors := make(map[string]int)
+	kindLatencies := make(map[string]*LatencyHistogram)
+
+	endpointAttempts := make(map[string]int)
+	endpointErrors := make(map[string]int)
+	endpointQueries := make(map[string]int)
+	endpointLatencies := make(map[string]*LatencyHistogram)
+
 	for result := range resultChan {
 		totalQueries += result.Queries
 		totalErrors += result.Errors
 		totalRecords += result.RecordsScanned
+		latencies.Merge(result.Latencies)
+		errorLatencies.Merge(result.ErrorLatencies)
+		throttledDuration += result.ThrottledDuration
+		throttleEvents += result.ThrottleEvents
+
+		for kind, n := range result.KindAttempts {
+			kindAttempts[kind] += n
+		}
+		for kind, n := range result.KindErrors {
+			kindErrors[kind] += n
+		}
+		for kind, h := range result.KindLatencies {
+			if kindLatencies[kind] == nil {
+				kindLatencies[kind] = newLatencyHistogram(t.Config.LatencyBuckets)
+			}
+			kindLatencies[kind].Merge(h)
+		}
+
+		if result.Endpoint != "" {
+			endpointAttempts[result.Endpoint] += result.Queries + result.Errors
+			endpointErrors[result.Endpoint] += result.Errors
+			endpointQueries[result.Endpoint] += result.Queries
+			if endpointLatencies[result.Endpoint] == nil {
+				endpointLatencies[result.Endpoint] = newLatencyHistogram(t.Config.LatencyBuckets)
+			}
+			endpointLatencies[result.Endpoint].Merge(result.Latencies)
+		}
+
+		if len(result.Windows) > len(windows) {
+			grown := make([]*LatencyHistogram, len(result.Windows))
+			copy(grown, windows)
+			windows = grown
+		}
+		for i, w := range result.Windows {
+			if w == nil {
+				continue
+			}
+			if windows[i] == nil {
+				windows[i] = newLatencyHistogram(t.Config.LatencyBuckets)
+			}
+			windows[i].Merge(w)
+		}
 	}
 
 	endTime := time.Now()
@@ -352,6 +1203,20 @@ func (t *TestRunner) RunTest(concurrency int) TestResult {
 		errorRate = float64(totalErrors) / float64(totalAttempts)
 	}
 
+	var series []TimeSeriesPoint
+	for second, w := range windows {
+		if w == nil || w.Total() == 0 {
+			continue
+		}
+		series = append(series, TimeSeriesPoint{
+			Second: second,
+			Count:  int(w.Total()),
+			MinMs:  durationMs(w.Min()),
+			AvgMs:  durationMs(w.Mean()),
+			P99Ms:  durationMs(w.Percentile(99)),
+		})
+	}
+
 	// Create result
 	result := TestResult{
 		Concurrency:         concurrency,
@@ -364,6 +1229,31 @@ func (t *TestRunner) RunTest(concurrency int) TestResult {
 		SuccessfulQPS:       successfulQPS,
 		RecordsPerSec:       recordsPerSec,
 		ErrorRate:           errorRate,
+
+		LatencyMinMs:  durationMs(latencies.Min()),
+		LatencyMeanMs: durationMs(latencies.Mean()),
+		LatencyP50Ms:  durationMs(latencies.Percentile(50)),
+		LatencyP95Ms:  durationMs(latencies.Percentile(95)),
+		LatencyP99Ms:  durationMs(latencies.Percentile(99)),
+		LatencyP999Ms: durationMs(latencies.Percentile(99.9)),
+		LatencyMaxMs:  durationMs(latencies.Max()),
+
+		ErrorLatencyMinMs:  durationMs(errorLatencies.Min()),
+		ErrorLatencyMeanMs: durationMs(errorLatencies.Mean()),
+		ErrorLatencyP50Ms:  durationMs(errorLatencies.Percentile(50)),
+		ErrorLatencyP95Ms:  durationMs(errorLatencies.Percentile(95)),
+		ErrorLatencyP99Ms:  durationMs(errorLatencies.Percentile(99)),
+		ErrorLatencyMaxMs:  durationMs(errorLatencies.Max()),
+
+		LatencyBucketCounts:      latencies.Buckets(),
+		ErrorLatencyBucketCounts: errorLatencies.Buckets(),
+		LatencyTimeSeries:        series,
+
+		ThrottledDurationSec: throttledDuration.Seconds(),
+		ThrottleEvents:       throttleEvents,
+
+		WorkloadResults: buildWorkloadResults(kindAttempts, kindErrors, kindLatencies),
+		EndpointResults: buildEndpointResults(endpointAttempts, endpointErrors, endpointQueries, endpointLatencies, actualDuration),
 	}
 
 	t.TestResults[concurrency] = result
@@ -380,6 +1270,50 @@ func (t *TestRunner) RunTest(concurrency int) TestResult {
 		fmt.Printf("  Successful QPS: %.2f\n", successfulQPS)
 	}
 	fmt.Printf("  Error rate: %.2f%%\n", errorRate*100)
+	if totalQueries > 0 {
+		fmt.Printf("  Latency (ms): min=%.2f mean=%.2f p50=%.2f p95=%.2f p99=%.2f p999=%.2f max=%.2f\n",
+			result.LatencyMinMs, result.LatencyMeanMs, result.LatencyP50Ms,
+			result.LatencyP95Ms, result.LatencyP99Ms, result.LatencyP999Ms, result.LatencyMaxMs)
+	}
+	if totalErrors > 0 {
+		fmt.Printf("  Error latency (ms): min=%.2f mean=%.2f p50=%.2f p95=%.2f p99=%.2f max=%.2f\n",
+			result.ErrorLatencyMinMs, result.ErrorLatencyMeanMs, result.ErrorLatencyP50Ms,
+			result.ErrorLatencyP95Ms, result.ErrorLatencyP99Ms, result.ErrorLatencyMaxMs)
+	}
+	if throttleEvents > 0 {
+		fmt.Printf("  Throttled: %.2fs across %d backoff events (error-rate based)\n",
+			result.ThrottledDurationSec, throttleEvents)
+	}
+	if len(result.WorkloadResults) > 1 {
+		kinds := make([]string, 0, len(result.WorkloadResults))
+		for kind := range result.WorkloadResults {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		fmt.Println("  Per-kind breakdown:")
+		for _, kind := range kinds {
+			kr := result.WorkloadResults[kind]
+			fmt.Printf("    %-16s attempts=%-8d errors=%-6d latency(ms): mean=%.2f p50=%.2f p95=%.2f p99=%.2f max=%.2f\n",
+				kind, kr.Attempts, kr.Errors, kr.MeanMs, kr.P50Ms, kr.P95Ms, kr.P99Ms, kr.MaxMs)
+		}
+	}
+	if len(result.EndpointResults) > 0 {
+		endpoints := make([]string, 0, len(result.EndpointResults))
+		for endpoint := range result.EndpointResults {
+			endpoints = append(endpoints, endpoint)
+		}
+		sort.Strings(endpoints)
+		fmt.Println("  Per-endpoint breakdown:")
+		for _, endpoint := range endpoints {
+			er := result.EndpointResults[endpoint]
+			fmt.Printf("    %-22s attempts=%-8d qps=%-9.2f error_rate=%.2f%% p99=%.2fms\n",
+				endpoint, er.Attempts, er.QPS, er.ErrorRate*100, er.P99Ms)
+		}
+	}
+
+	if ctx.Err() != nil {
+		return result
+	}
 
 	// Cooldown
 	fmt.Printf("Waiting for system cooldown, %d seconds...\n", t.Config.Cooldown)
@@ -388,10 +1322,149 @@ func (t *TestRunner) RunTest(concurrency int) TestResult {
 	return result
 }
 
+// checkpointFilePath is where RunAllTests persists progress across
+// concurrency levels, so a crash or Ctrl-C loses at most the in-flight
+// level instead of the whole matrix.
+const checkpointFilePath = "future_ts_test_results.checkpoint.json"
+
+// checkpointFingerprint captures the subset of TestConfig that determines
+// whether a checkpointed level's result is still valid to reuse. Fields
+// like ConcurrencyLevels, Cooldown, or Verbose don't change what was
+// already measured against the table, so they're left out of the hash.
+// Workload isn't hashable (it's an interface), so switching --workload-file
+// between runs against the same table isn't detected here - pass --resume
+// only when reusing the same workload.
+type checkpointFingerprint struct {
+	Endpoints   []string
+	Database    string
+	TableName   string
+	Rows        int
+	RegionCount int
+	FutureTS    int
+	Duration    int
+}
+
+// configCheckpointKey returns a stable identifier for the test configuration
+// a checkpoint belongs to.
+func configCheckpointKey(config TestConfig) string {
+	fp := checkpointFingerprint{
+		Endpoints:   config.Endpoints,
+		Database:    config.Database,
+		TableName:   config.TableName,
+		Rows:        config.Rows,
+		RegionCount: config.RegionCount,
+		FutureTS:    config.FutureTS,
+		Duration:    config.Duration,
+	}
+	data, _ := json.Marshal(fp)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkpointFile is the on-disk shape of checkpointFilePath.
+type checkpointFile struct {
+	ConfigHash string             `json:"config_hash"`
+	Timestamp  string             `json:"timestamp"`
+	Results    map[int]TestResult `json:"results"`
+}
+
+// loadCheckpoint reads checkpointFilePath, returning (nil, nil) if it
+// doesn't exist.
+func loadCheckpoint(path string) (*checkpointFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var cp checkpointFile
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("parsing checkpoint file %s: %w", path, err)
+	}
+	return &cp, nil
+}
+
+// saveCheckpoint atomically writes the checkpoint file (write to a temp
+// file, then rename), so a crash mid-write can't corrupt the previous
+// checkpoint.
+func saveCheckpoint(path string, cp checkpointFile) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// loadResumableResults loads completed (non-partial) levels from an
+// existing checkpoint whose config hash matches config, for RunAllTests to
+// skip. It's a no-op (returns nil) if config.Resume is false, no checkpoint
+// exists, or the checkpoint belongs to a different configuration.
+func loadResumableResults(config TestConfig) map[int]TestResult {
+	if !config.Resume {
+		return nil
+	}
+	cp, err := loadCheckpoint(checkpointFilePath)
+	if err != nil {
+		fmt.Printf("WARNING: failed to read checkpoint %s: %v\n", checkpointFilePath, err)
+		return nil
+	}
+	if cp == nil {
+		return nil
+	}
+	if cp.ConfigHash != configCheckpointKey(config) {
+		fmt.Printf("Checkpoint %s belongs to a different configuration, ignoring\n", checkpointFilePath)
+		return nil
+	}
+	resumed := make(map[int]TestResult)
+	for concurrency, result := range cp.Results {
+		if !result.Partial {
+			resumed[concurrency] = result
+		}
+	}
+	return resumed
+}
+
 // RunAllTests runs tests for all specified concurrency levels
-func (t *TestRunner) RunAllTests() {
+func (t *TestRunner) RunAllTests(ctx context.Context) {
+	for concurrency, result := range loadResumableResults(t.Config) {
+		if _, done := t.TestResults[concurrency]; !done {
+			fmt.Printf("Resuming from checkpoint: concurrency %d already completed, skipping\n", concurrency)
+			t.TestResults[concurrency] = result
+		}
+	}
+
 	for _, concurrency := range t.Config.ConcurrencyLevels {
-		t.RunTest(concurrency)
+		if _, done := t.TestResults[concurrency]; done {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		result := t.RunTest(ctx, concurrency)
+		interrupted := ctx.Err() != nil
+		result.Partial = interrupted
+		t.TestResults[concurrency] = result
+
+		if err := saveCheckpoint(checkpointFilePath, checkpointFile{
+			ConfigHash: configCheckpointKey(t.Config),
+			Timestamp:  time.Now().UTC().Format(time.RFC3339),
+			Results:    t.TestResults,
+		}); err != nil {
+			fmt.Printf("WARNING: failed to save checkpoint: %v\n", err)
+		} else {
+			fmt.Printf("Checkpoint saved to %s\n", checkpointFilePath)
+		}
+
+		if interrupted {
+			fmt.Printf("Test run interrupted; concurrency %d saved as partial. Re-run with --resume to pick up where this left off.\n", concurrency)
+			break
+		}
 	}
 
 	t.GenerateReport()
@@ -414,8 +1487,8 @@ func (t *TestRunner) GenerateReport() {
 	// Headers
 	fmt.Println("Test Results:")
 	fmt.Println("--------------------------------------------------------------------------------")
-	fmt.Printf("%-10s %-15s %-15s %-10s %-12s %-15s\n",
-		"Concurrency", "Total Attempts", "Success", "Errors", "Attempts/sec", "Error Rate(%)")
+	fmt.Printf("%-10s %-15s %-15s %-10s %-12s %-15s %s\n",
+		"Concurrency", "Total Attempts", "Success", "Errors", "Attempts/sec", "Error Rate(%)", "Status")
 	fmt.Println("--------------------------------------------------------------------------------")
 
 	// Sort concurrency levels
@@ -428,16 +1501,66 @@ func (t *TestRunner) GenerateReport() {
 	// Print results
 	for _, concurrency := range levels {
 		result := t.TestResults[concurrency]
-		fmt.Printf("%-10d %-15d %-15d %-10d %-12.2f %-15.2f%%\n",
+		status := "complete"
+		if result.Partial {
+			status = "PARTIAL (interrupted)"
+		}
+		fmt.Printf("%-10d %-15d %-15d %-10d %-12.2f %-15.2f%% %s\n",
 			concurrency,
 			result.TotalAttempts,
 			result.TotalQueries,
 			result.TotalErrors,
 			result.AttemptsPerSec,
-			result.ErrorRate*100)
+			result.ErrorRate*100,
+			status)
 	}
 	fmt.Println("--------------------------------------------------------------------------------")
 
+	// Latency table
+	fmt.Println("\nLatency (ms):")
+	fmt.Println("--------------------------------------------------------------------------------")
+	fmt.Printf("%-10s %-8s %-8s %-8s %-8s %-8s %-8s %-8s\n",
+		"Concurr.", "Min", "Mean", "P50", "P95", "P99", "P999", "Max")
+	fmt.Println("--------------------------------------------------------------------------------")
+	for _, concurrency := range levels {
+		result := t.TestResults[concurrency]
+		fmt.Printf("%-10d %-8.2f %-8.2f %-8.2f %-8.2f %-8.2f %-8.2f %-8.2f\n",
+			concurrency, result.LatencyMinMs, result.LatencyMeanMs, result.LatencyP50Ms,
+			result.LatencyP95Ms, result.LatencyP99Ms, result.LatencyP999Ms, result.LatencyMaxMs)
+	}
+	fmt.Println("--------------------------------------------------------------------------------")
+	fmt.Println("Per-second latency/count time series and raw histogram buckets are in future_ts_test_results.json.")
+
+	// Per-endpoint breakdown, only meaningful with more than one endpoint
+	hasEndpointResults := false
+	for _, concurrency := range levels {
+		if len(t.TestResults[concurrency].EndpointResults) > 0 {
+			hasEndpointResults = true
+			break
+		}
+	}
+	if hasEndpointResults {
+		fmt.Println("\nPer-Endpoint Breakdown:")
+		fmt.Println("--------------------------------------------------------------------------------")
+		fmt.Printf("%-10s %-22s %-10s %-10s %-12s %-10s\n",
+			"Concurr.", "Endpoint", "Attempts", "QPS", "Error Rate(%)", "P99(ms)")
+		fmt.Println("--------------------------------------------------------------------------------")
+		for _, concurrency := range levels {
+			result := t.TestResults[concurrency]
+			var endpoints []string
+			for endpoint := range result.EndpointResults {
+				endpoints = append(endpoints, endpoint)
+			}
+			sort.Strings(endpoints)
+			for _, endpoint := range endpoints {
+				er := result.EndpointResults[endpoint]
+				fmt.Printf("%-10d %-22s %-10d %-10.2f %-12.2f %-10.2f\n",
+					concurrency, endpoint, er.Attempts, er.QPS, er.ErrorRate*100, er.P99Ms)
+			}
+		}
+		fmt.Println("--------------------------------------------------------------------------------")
+	}
+
 	// Save raw results
 	jsonData, err := json.MarshalIndent(t.TestResults, "", "  ")
 	if err != nil {
@@ -483,8 +1606,10 @@ func parseConcurrencyLevels(s string) ([]int, error) {
 
 func main() {
 	// Parse command line arguments
-	host := flag.String("host", "127.0.0.1", "TiDB host")
-	port := flag.Int("port", 4000, "TiDB port")
+	host := flag.String("host", "127.0.0.1", "TiDB host (shorthand for -endpoints when only one server is used)")
+	port := flag.Int("port", 4000, "TiDB port (shorthand for -endpoints when only one server is used)")
+	endpointsStr := flag.String("endpoints", "", "Comma-separated list of TiDB \"host:port\" endpoints to distribute clients across (overrides -host/-port)")
+	endpointStrategy := flag.String("endpoint-strategy", "round-robin", "How clients pick an endpoint when -endpoints has more than one: \"round-robin\" or \"random\"")
 	user := flag.String("user", "root", "TiDB username")
 	password := flag.String("password", "", "TiDB password")
 	database := flag.String("database", "test", "Database name")
@@ -496,6 +1621,13 @@ func main() {
 	futureTS := flag.Int("future-ts", 1000, "Fixed future timestamp in milliseconds")
 	cooldown := flag.Int("cooldown", 60, "Cooldown time between tests (seconds)")
 	verbose := flag.Bool("verbose", false, "Enable verbose logging")
+	latencyBuckets := flag.Int("latency-buckets", 2, "Significant decimal digits of latency histogram resolution (e.g. 2 or 3)")
+	throttleErrorRate := flag.Float64("throttle-error-rate", 0.2, "Rolling error rate above which a client backs off instead of retrying immediately (0 disables rate-based throttling)")
+	throttleMaxDelay := flag.Duration("throttle-max-delay", 5*time.Second, "Maximum backoff delay between query attempts")
+	throttleRecoveryHalfLife := flag.Duration("throttle-recovery-half-life", time.Second, "Half-life for the backoff delay to decay once the error rate recovers")
+	throttleDisable := flag.Bool("throttle-disable", false, "Disable adaptive throttling and restore the fixed 100ms sleep per error")
+	workloadFile := flag.String("workload-file", "", "Path to a YAML/JSON workload spec with weighted query templates (default: the builtin fixed-future-timestamp query)")
+	resume := flag.Bool("resume", false, "Resume from future_ts_test_results.checkpoint.json, skipping concurrency levels it already completed for this configuration")
 
 	flag.Parse()
 
@@ -505,10 +1637,25 @@ func main() {
 		log.Fatalf("Invalid concurrency levels: %v", err)
 	}
 
+	var endpoints []string
+	if *endpointsStr != "" {
+		for _, e := range strings.Split(*endpointsStr, ",") {
+			e = strings.TrimSpace(e)
+			if e != "" {
+				endpoints = append(endpoints, e)
+			}
+		}
+	} else {
+		endpoints = []string{fmt.Sprintf("%s:%d", *host, *port)}
+	}
+	if *endpointStrategy != "round-robin" && *endpointStrategy != "random" {
+		log.Fatalf("Invalid -endpoint-strategy %q: must be \"round-robin\" or \"random\"", *endpointStrategy)
+	}
+
 	config := TestConfig{
-		Host:              *host,
-		Port:              *port,
-		User:              *user,
+		Endpoints:        endpoints,
+		EndpointStrategy: *endpointStrategy,
+		User:             *user,
 		Password:          *password,
 		Database:          *database,
 		TableName:         *tableName,
@@ -519,16 +1666,36 @@ func main() {
 		FutureTS:          *futureTS,
 		Cooldown:          *cooldown,
 		Verbose:           *verbose,
+		LatencyBuckets:    *latencyBuckets,
+
+		ThrottleErrorRate:        *throttleErrorRate,
+		ThrottleMaxDelay:         *throttleMaxDelay,
+		ThrottleRecoveryHalfLife: *throttleRecoveryHalfLife,
+		ThrottleDisable:          *throttleDisable,
+		Resume:                   *resume,
+	}
+
+	if *workloadFile != "" {
+		workload, err := loadWorkloadFile(*workloadFile)
+		if err != nil {
+			log.Fatalf("Failed to load workload file: %v", err)
+		}
+		config.Workload = workload
 	}
 
-	// Setup signal handler
+	// Setup signal handler. SIGINT/SIGTERM cancel the run context instead
+	// of exiting immediately, so RunAllTests can checkpoint the
+	// in-progress concurrency level as partial before returning.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
 		<-sigChan
-		fmt.Println("Test interrupted")
-		os.Exit(0)
+		fmt.Println("\nInterrupt received, stopping the current concurrency level and checkpointing it as partial...")
+		cancel()
 	}()
 
 	// Run test
@@ -548,5 +1715,5 @@ func main() {
 		}
 	}
 
-	runner.RunAllTests()
+	runner.RunAllTests(ctx)
 }
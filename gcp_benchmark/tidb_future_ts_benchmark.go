@@ -1,33 +1,305 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
 	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
 	"log"
+	"math"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
+	"github.com/golang/snappy"
+	promapi "github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/prompb"
+	writev2 "github.com/prometheus/prometheus/prompb/io/prometheus/write/v2"
+	"gopkg.in/yaml.v3"
 )
 
+// Latency histogram configuration: a log-linear (HDR-style) histogram covering
+// 1 microsecond to 60 seconds at ~2 significant digits of resolution. This is
+// cheap enough to update per-query under high concurrency without shipping raw
+// samples across the result channel.
+const (
+	latencyHistMinMicros = 1.0
+	latencyHistMaxMicros = 60 * 1000000.0
+	latencyHistSigDigits = 2
+)
+
+var (
+	latencyHistFactor      = math.Pow(10, 1.0/math.Pow(10, latencyHistSigDigits))
+	latencyHistBucketCount = int(math.Ceil(math.Log(latencyHistMaxMicros/latencyHistMinMicros)/math.Log(latencyHistFactor))) + 1
+)
+
+// LatencyHistogram is an additive, fixed-size log-linear histogram of query
+// latencies. Because buckets are additive, per-client histograms can be
+// merged with a simple bucket-wise sum.
+type LatencyHistogram struct {
+	counts []uint64
+}
+
+// newLatencyHistogram creates an empty histogram.
+func newLatencyHistogram() *LatencyHistogram {
+	return &LatencyHistogram{counts: make([]uint64, latencyHistBucketCount)}
+}
+
+func latencyBucketIndex(d time.Duration) int {
+	micros := float64(d.Microseconds())
+	if micros < latencyHistMinMicros {
+		micros = latencyHistMinMicros
+	}
+	if micros > latencyHistMaxMicros {
+		micros = latencyHistMaxMicros
+	}
+	idx := int(math.Log(micros/latencyHistMinMicros) / math.Log(latencyHistFactor))
+	if idx >= latencyHistBucketCount {
+		idx = latencyHistBucketCount - 1
+	}
+	return idx
+}
+
+func latencyBucketUpperMicros(idx int) float64 {
+	return latencyHistMinMicros * math.Pow(latencyHistFactor, float64(idx+1))
+}
+
+// Record adds a single observed latency to the histogram.
+func (h *LatencyHistogram) Record(d time.Duration) {
+	h.counts[latencyBucketIndex(d)]++
+}
+
+// Merge bucket-wise adds another histogram's counts into this one.
+func (h *LatencyHistogram) Merge(other *LatencyHistogram) {
+	if other == nil {
+		return
+	}
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+}
+
+// Total returns the number of samples recorded.
+func (h *LatencyHistogram) Total() uint64 {
+	var total uint64
+	for _, c := range h.counts {
+		total += c
+	}
+	return total
+}
+
+// Percentile returns the latency at percentile p (0-100).
+func (h *LatencyHistogram) Percentile(p float64) time.Duration {
+	total := h.Total()
+	if total == 0 {
+		return 0
+	}
+	target := uint64(math.Ceil(p / 100 * float64(total)))
+	if target == 0 {
+		target = 1
+	}
+	var cumulative uint64
+	for i, c := range h.counts {
+		cumulative += c
+		if cumulative >= target {
+			return time.Duration(latencyBucketUpperMicros(i) * float64(time.Microsecond))
+		}
+	}
+	return time.Duration(latencyBucketUpperMicros(latencyHistBucketCount-1) * float64(time.Microsecond))
+}
+
+// Max returns the latency of the highest non-empty bucket.
+func (h *LatencyHistogram) Max() time.Duration {
+	for i := latencyHistBucketCount - 1; i >= 0; i-- {
+		if h.counts[i] > 0 {
+			return time.Duration(latencyBucketUpperMicros(i) * float64(time.Microsecond))
+		}
+	}
+	return 0
+}
+
+// HistogramBucket is a sparse (non-empty-only) JSON representation of a
+// LatencyHistogram, keyed by the bucket's upper bound.
+type HistogramBucket struct {
+	LeMs  float64 `json:"le_ms"`
+	Count uint64  `json:"count"`
+}
+
+// Buckets returns the non-empty buckets for JSON export.
+func (h *LatencyHistogram) Buckets() []HistogramBucket {
+	var out []HistogramBucket
+	for i, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		out = append(out, HistogramBucket{LeMs: latencyBucketUpperMicros(i) / 1000.0, Count: c})
+	}
+	return out
+}
+
+// MySQLConnectParam bundles everything needed to open a tuned connection to
+// TiDB: the basic DSN fields, TLS, pool sizing, timeouts, and session
+// variables to apply once connected. Centralizing this means Connect,
+// SplitTable and RunClient - which each used to build their own DSN string -
+// share one code path instead of drifting apart.
+type MySQLConnectParam struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Database string
+
+	// TLS is enabled when all three paths are set; the resulting tls.Config
+	// is registered with the driver under TLSConfigName (default
+	// "tidb-bench") and referenced from the DSN's tls= parameter.
+	TLSCAPath     string
+	TLSCertPath   string
+	TLSKeyPath    string
+	TLSConfigName string
+
+	MaxAllowedPacket int // bytes; 0 uses the driver default
+	DialTimeout      time.Duration
+	ReadTimeout      time.Duration
+	WriteTimeout     time.Duration
+	ConnMaxLifetime  time.Duration
+	MaxOpenConns     int
+	MaxIdleConns     int
+
+	// Vars holds session variables the driver applies ("SET <k>=<v>") on
+	// every new connection in the pool, e.g. tidb_enable_async_commit,
+	// tidb_read_staleness, tidb_replica_read.
+	Vars map[string]string
+}
+
+func (p MySQLConnectParam) tlsEnabled() bool {
+	return p.TLSCAPath != "" && p.TLSCertPath != "" && p.TLSKeyPath != ""
+}
+
+func (p MySQLConnectParam) tlsConfigName() string {
+	if p.TLSConfigName != "" {
+		return p.TLSConfigName
+	}
+	return "tidb-bench"
+}
+
+// registerTLSConfig loads the CA/cert/key triplet and registers it with the
+// mysql driver under tlsConfigName(), so dsn()'s tls=<name> parameter can
+// reference it. A no-op when TLS isn't configured.
+func (p MySQLConnectParam) registerTLSConfig() error {
+	if !p.tlsEnabled() {
+		return nil
+	}
+	caCert, err := os.ReadFile(p.TLSCAPath)
+	if err != nil {
+		return fmt.Errorf("failed to read TLS CA %s: %v", p.TLSCAPath, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return fmt.Errorf("failed to parse TLS CA %s", p.TLSCAPath)
+	}
+	cert, err := tls.LoadX509KeyPair(p.TLSCertPath, p.TLSKeyPath)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS cert/key: %v", err)
+	}
+	return mysql.RegisterTLSConfig(p.tlsConfigName(), &tls.Config{
+		RootCAs:      pool,
+		Certificates: []tls.Certificate{cert},
+	})
+}
+
+// dsn builds the go-sql-driver/mysql DSN for these parameters. dialTimeout is
+// passed separately because Connect substitutes a 10s default when
+// DialTimeout is unset; SplitTable and RunClient get a longer timeout by
+// setting DialTimeout on their own copy of the parameters.
+func (p MySQLConnectParam) dsn(dialTimeout time.Duration) string {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?timeout=%s&autocommit=true",
+		p.User, p.Password, p.Host, p.Port, p.Database, dialTimeout)
+	if p.MaxAllowedPacket > 0 {
+		dsn += fmt.Sprintf("&maxAllowedPacket=%d", p.MaxAllowedPacket)
+	}
+	if p.ReadTimeout > 0 {
+		dsn += fmt.Sprintf("&readTimeout=%s", p.ReadTimeout)
+	}
+	if p.WriteTimeout > 0 {
+		dsn += fmt.Sprintf("&writeTimeout=%s", p.WriteTimeout)
+	}
+	if p.tlsEnabled() {
+		dsn += fmt.Sprintf("&tls=%s", p.tlsConfigName())
+	}
+	// The driver treats any other DSN parameter as a system variable and
+	// SETs it on every connection it opens.
+	for name, value := range p.Vars {
+		dsn += fmt.Sprintf("&%s=%s", name, url.QueryEscape(value))
+	}
+	return dsn
+}
+
+// Connect opens a pooled *sql.DB per these parameters: registers TLS (if
+// configured), applies the pool/timeout tunables and pings to confirm
+// connectivity. Vars travel in the DSN (see dsn), so every connection the
+// pool opens, including the one the ping uses, has them applied.
+func (p MySQLConnectParam) Connect(ctx context.Context) (*sql.DB, error) {
+	if err := p.registerTLSConfig(); err != nil {
+		return nil, err
+	}
+
+	dialTimeout := p.DialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = 10 * time.Second
+	}
+
+	db, err := sql.Open("mysql", p.dsn(dialTimeout))
+	if err != nil {
+		return nil, err
+	}
+
+	maxOpenConns := p.MaxOpenConns
+	if maxOpenConns <= 0 {
+		maxOpenConns = 200
+	}
+	maxIdleConns := p.MaxIdleConns
+	if maxIdleConns <= 0 {
+		maxIdleConns = 100
+	}
+	connMaxLifetime := p.ConnMaxLifetime
+	if connMaxLifetime <= 0 {
+		connMaxLifetime = time.Hour
+	}
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetConnMaxLifetime(connMaxLifetime)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
 // TestConfig holds all configuration parameters for the test
 type TestConfig struct {
-	Host              string
-	Port              int
-	User              string
-	Password          string
-	Database          string
+	MySQLConnectParam
 	TableName         string
 	Rows              int
 	RegionCount       int
@@ -42,6 +314,34 @@ type TestConfig struct {
 	PrometheusAddr    string // Prometheus server address
 	PrometheusPort    int    // Prometheus server port
 	CollectMetrics    bool   // Whether to collect Prometheus metrics
+	MetricsConfigPath string // Optional JSON file of extra MetricSeriesConfig to sample
+	MetricsStep       int    // Step (seconds) for Prometheus query_range sampling
+
+	OutputFormats   []string // Any of: text, json, jsonl, csv, pushgateway, markdown
+	PushgatewayURL  string   // Pushgateway base URL, required when OutputFormats includes "pushgateway"
+
+	// Remote-write push, so results show up in Grafana alongside the
+	// cluster's own PD/TiKV metrics instead of diffing text reports.
+	RemoteWriteURL string
+	RemoteWriteV2  bool
+	RunID          string // Distinguishes samples from different sweep invocations
+
+	// Checkpoint/resume for long multi-configuration sweeps. CheckpointPath
+	// enables checkpointing when non-empty; CheckpointDriver selects where the
+	// state lives ("file" or "mysql", i.e. the target TiDB itself).
+	CheckpointPath   string
+	CheckpointDriver string
+	Restart          bool // Ignore any existing checkpoint entries for this config
+
+	Workload       string // Name of the workload to run (see newWorkload)
+	WorkloadRows   int    // Row count used by range-scan workloads
+	WorkloadStaleMS int   // Staleness offset (ms) used by the stale-read workload
+
+	// Open-loop load generator settings (Mode == "open")
+	Mode        string  // "closed" (default, goroutine-per-client) or "open"
+	TargetQPS   float64 // Target arrival rate for open-loop mode
+	ArrivalDist string  // "poisson" (default) or "uniform"
+	MaxInflight int     // Bound on concurrently in-flight requests; 0 means use the concurrency level
 }
 
 // TestResult holds the results of a single test run at a specific concurrency level
@@ -59,6 +359,35 @@ type TestResult struct {
 	SplitRegions        bool    `json:"split_regions"`
 	UseFutureTS         bool    `json:"use_future_ts"`
 	TSORequests         float64 `json:"tso_requests"`
+
+	// PD-side TSO handling latency, read from the
+	// pd_client_request_handle_requests_duration_seconds histogram rather
+	// than measured client-side. Zero (and omitted) when CollectMetrics is
+	// off or the histogram metric isn't present on this cluster.
+	TSOLatencyP50Ms float64 `json:"tso_latency_p50_ms,omitempty"`
+	TSOLatencyP95Ms float64 `json:"tso_latency_p95_ms,omitempty"`
+	TSOLatencyP99Ms float64 `json:"tso_latency_p99_ms,omitempty"`
+
+	LatencyP50Ms    float64           `json:"latency_p50_ms"`
+	LatencyP90Ms    float64           `json:"latency_p90_ms"`
+	LatencyP95Ms    float64           `json:"latency_p95_ms"`
+	LatencyP99Ms    float64           `json:"latency_p99_ms"`
+	LatencyP999Ms   float64           `json:"latency_p999_ms"`
+	LatencyMaxMs    float64           `json:"latency_max_ms"`
+	LatencyBuckets  []HistogramBucket `json:"latency_buckets,omitempty"`
+
+	Workload string `json:"workload"`
+
+	// Scheduled latency is only populated in open-loop mode (Mode == "open")
+	// and measures from the intended dispatch time rather than the actual
+	// send time, so client-side backlog shows up in the tail instead of
+	// being hidden by coordinated omission.
+	ScheduledLatencyP50Ms  float64 `json:"scheduled_latency_p50_ms,omitempty"`
+	ScheduledLatencyP95Ms  float64 `json:"scheduled_latency_p95_ms,omitempty"`
+	ScheduledLatencyP99Ms  float64 `json:"scheduled_latency_p99_ms,omitempty"`
+	ScheduledLatencyMaxMs  float64 `json:"scheduled_latency_max_ms,omitempty"`
+
+	MetricSeries []MetricSeriesResult `json:"metric_series,omitempty"`
 }
 
 // ClientResult holds the results from a single test client
@@ -66,6 +395,7 @@ type ClientResult struct {
 	Queries        int
 	Errors         int
 	RecordsScanned int
+	Latencies      *LatencyHistogram
 }
 
 // TestRunner orchestrates the entire test process
@@ -73,6 +403,12 @@ type TestRunner struct {
 	Config      TestConfig
 	DB          *sql.DB
 	TestResults map[int]TestResult
+	Metrics     *MetricsCollector
+	Checkpoint  CheckpointStore
+	PromClient  *metricsClient
+	// Ctx is cancelled by main's signal handler so in-flight Prometheus
+	// queries stop promptly instead of outliving the process.
+	Ctx context.Context
 }
 
 // TestRunnerGroup holds all test runners for different configurations
@@ -85,6 +421,7 @@ func NewTestRunner(config TestConfig) *TestRunner {
 	return &TestRunner{
 		Config:      config,
 		TestResults: make(map[int]TestResult),
+		Ctx:         context.Background(),
 	}
 }
 
@@ -102,25 +439,11 @@ func (g *TestRunnerGroup) AddRunner(runner *TestRunner) {
 
 // Connect establishes a connection to the TiDB database
 func (t *TestRunner) Connect() error {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?timeout=10s&autocommit=true",
-		t.Config.User, t.Config.Password, t.Config.Host, t.Config.Port, t.Config.Database)
-
-	var err error
-	t.DB, err = sql.Open("mysql", dsn)
-	if err != nil {
-		return err
-	}
-
-	// Configure connection pool
-	t.DB.SetMaxOpenConns(200) // Adjust based on your system capacity
-	t.DB.SetMaxIdleConns(100)
-	t.DB.SetConnMaxLifetime(time.Hour)
-
-	// Test connection
-	err = t.DB.Ping()
+	db, err := t.Config.MySQLConnectParam.Connect(t.Ctx)
 	if err != nil {
 		return err
 	}
+	t.DB = db
 
 	fmt.Printf("Connected to TiDB: %s:%d\n", t.Config.Host, t.Config.Port)
 	return nil
@@ -136,6 +459,18 @@ func (t *TestRunner) Close() {
 
 // SetupTable creates and populates the test table
 func (t *TestRunner) SetupTable() error {
+	checkpointKey := configCheckpointKey(t.Config)
+	if t.Checkpoint != nil && !t.Config.Restart {
+		done, err := t.Checkpoint.IsSetupDone(checkpointKey)
+		if err != nil {
+			return fmt.Errorf("failed to read checkpoint: %v", err)
+		}
+		if done {
+			fmt.Println("Checkpoint: table setup already completed for this configuration, skipping")
+			return nil
+		}
+	}
+
 	// Drop existing table if any
 	_, err := t.DB.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", t.Config.TableName))
 	if err != nil {
@@ -208,6 +543,12 @@ func (t *TestRunner) SetupTable() error {
 		fmt.Println("Skipping table split as per configuration")
 	}
 
+	if t.Checkpoint != nil {
+		if err := t.Checkpoint.MarkSetupDone(checkpointKey); err != nil {
+			fmt.Printf("WARNING: failed to persist setup checkpoint: %v\n", err)
+		}
+	}
+
 	return nil
 }
 
@@ -215,11 +556,12 @@ func (t *TestRunner) SetupTable() error {
 func (t *TestRunner) SplitTable() error {
 	fmt.Printf("Splitting table into %d regions...\n", t.Config.RegionCount)
 
-	// Use a separate connection for SPLIT operations
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?timeout=300s&autocommit=true",
-		t.Config.User, t.Config.Password, t.Config.Host, t.Config.Port, t.Config.Database)
+	// Use a separate connection for SPLIT operations, with a longer dial
+	// timeout since region splitting on a large table can be slow.
+	splitParam := t.Config.MySQLConnectParam
+	splitParam.DialTimeout = 300 * time.Second
 
-	splitDB, err := sql.Open("mysql", dsn)
+	splitDB, err := splitParam.Connect(t.Ctx)
 	if err != nil {
 		return fmt.Errorf("failed to open split connection: %v", err)
 	}
@@ -249,20 +591,246 @@ func (t *TestRunner) SplitTable() error {
 	return nil
 }
 
+// Workload abstracts the query pattern a client issues against the test
+// table, so RunClient doesn't have to hard-code a single query shape.
+type Workload interface {
+	// Name identifies the workload, used for result breakdowns and reports.
+	Name() string
+	// Prepare runs any one-time setup the workload needs before clients start
+	// (e.g. seeding extra rows for a mixed read/write workload).
+	Prepare(db *sql.DB) error
+	// Vars returns any session variables this workload requires on every
+	// client connection (e.g. tidb_replica_read=follower), merged into
+	// MySQLConnectParam.Vars so they're applied once per connection the same
+	// way as any other configured session variable. nil if none are needed.
+	Vars() map[string]string
+	// Exec issues one unit of work on conn and returns the number of records
+	// it touched.
+	Exec(ctx context.Context, conn *sql.DB) (records int, err error)
+}
+
+// newWorkload builds the Workload named by config.Workload.
+func newWorkload(name string, config TestConfig) (Workload, error) {
+	switch name {
+	case "", "count":
+		return &countWorkload{config: config}, nil
+	case "point-get":
+		return &pointGetWorkload{config: config}, nil
+	case "range-scan":
+		return &rangeScanWorkload{config: config}, nil
+	case "stale-read":
+		return &staleReadWorkload{config: config}, nil
+	case "follower-read":
+		return &followerReadWorkload{config: config}, nil
+	case "mixed":
+		return &mixedWorkload{config: config}, nil
+	default:
+		return nil, fmt.Errorf("unknown workload %q", name)
+	}
+}
+
+// countWorkload is the tool's original behavior: SELECT COUNT(*), optionally
+// reading as of a fixed future timestamp.
+type countWorkload struct {
+	config TestConfig
+}
+
+func (w *countWorkload) Name() string          { return "count" }
+func (w *countWorkload) Prepare(db *sql.DB) error { return nil }
+func (w *countWorkload) Vars() map[string]string { return nil }
+
+func (w *countWorkload) Exec(ctx context.Context, conn *sql.DB) (int, error) {
+	var query string
+	if w.config.UseFutureTS {
+		microseconds := w.config.FutureTS * 1000
+		futureTS := fmt.Sprintf("NOW() + INTERVAL %d MICROSECOND", microseconds)
+		query = fmt.Sprintf("SELECT COUNT(*) FROM %s AS OF TIMESTAMP %s", w.config.TableName, futureTS)
+	} else {
+		query = fmt.Sprintf("SELECT COUNT(*) FROM %s", w.config.TableName)
+	}
+
+	var count int
+	if err := conn.QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// pointGetWorkload looks up a single random primary key.
+type pointGetWorkload struct {
+	config TestConfig
+}
+
+func (w *pointGetWorkload) Name() string          { return "point-get" }
+func (w *pointGetWorkload) Prepare(db *sql.DB) error { return nil }
+func (w *pointGetWorkload) Vars() map[string]string { return nil }
+
+func (w *pointGetWorkload) Exec(ctx context.Context, conn *sql.DB) (int, error) {
+	id := rand.Intn(w.config.Rows)
+	query := fmt.Sprintf("SELECT id FROM %s WHERE id = %d", w.config.TableName, id)
+	var found int
+	err := conn.QueryRowContext(ctx, query).Scan(&found)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// rangeScanWorkload scans WorkloadRows rows starting from a random offset.
+type rangeScanWorkload struct {
+	config TestConfig
+}
+
+func (w *rangeScanWorkload) Name() string          { return "range-scan" }
+func (w *rangeScanWorkload) Prepare(db *sql.DB) error { return nil }
+func (w *rangeScanWorkload) Vars() map[string]string { return nil }
+
+func (w *rangeScanWorkload) Exec(ctx context.Context, conn *sql.DB) (int, error) {
+	start := rand.Intn(w.config.Rows)
+	query := fmt.Sprintf("SELECT id FROM %s WHERE id >= %d ORDER BY id LIMIT %d",
+		w.config.TableName, start, w.config.WorkloadRows)
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		count++
+	}
+	return count, rows.Err()
+}
+
+// staleReadWorkload issues a point-get AS OF TIMESTAMP in the past, useful
+// for comparing stale-read cost against the future-TS workload.
+type staleReadWorkload struct {
+	config TestConfig
+}
+
+func (w *staleReadWorkload) Name() string          { return "stale-read" }
+func (w *staleReadWorkload) Prepare(db *sql.DB) error { return nil }
+func (w *staleReadWorkload) Vars() map[string]string { return nil }
+
+func (w *staleReadWorkload) Exec(ctx context.Context, conn *sql.DB) (int, error) {
+	id := rand.Intn(w.config.Rows)
+	query := fmt.Sprintf("SELECT id FROM %s AS OF TIMESTAMP NOW() - INTERVAL %d MICROSECOND WHERE id = %d",
+		w.config.TableName, w.config.WorkloadStaleMS*1000, id)
+
+	var found int
+	err := conn.QueryRowContext(ctx, query).Scan(&found)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// followerReadWorkload point-gets via tidb_replica_read=follower, to compare
+// how often a follower read avoids talking to PD for a TSO. The replica-read
+// variable is applied once per connection (see Vars), not per query, so its
+// cost doesn't show up as extra round trips in the measured latency.
+type followerReadWorkload struct {
+	config TestConfig
+}
+
+func (w *followerReadWorkload) Name() string { return "follower-read" }
+
+func (w *followerReadWorkload) Prepare(db *sql.DB) error { return nil }
+
+func (w *followerReadWorkload) Vars() map[string]string {
+	return map[string]string{"tidb_replica_read": "'follower'"}
+}
+
+func (w *followerReadWorkload) Exec(ctx context.Context, conn *sql.DB) (int, error) {
+	id := rand.Intn(w.config.Rows)
+	query := fmt.Sprintf("SELECT id FROM %s WHERE id = %d", w.config.TableName, id)
+
+	var found int
+	if err := conn.QueryRowContext(ctx, query).Scan(&found); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return 1, nil
+}
+
+// mixedWorkload interleaves INSERTs with SELECTs, similar to the
+// taosdemo-style read/write loop, to model a less read-only workload.
+type mixedWorkload struct {
+	config  TestConfig
+	nextID  int64
+	calls   int64
+}
+
+func (w *mixedWorkload) Name() string { return "mixed" }
+func (w *mixedWorkload) Vars() map[string]string { return nil }
+
+func (w *mixedWorkload) Prepare(db *sql.DB) error {
+	var maxID int64
+	query := fmt.Sprintf("SELECT COALESCE(MAX(id), %d) FROM %s", w.config.Rows, w.config.TableName)
+	if err := db.QueryRow(query).Scan(&maxID); err != nil {
+		return err
+	}
+	atomic.StoreInt64(&w.nextID, maxID+1)
+	return nil
+}
+
+func (w *mixedWorkload) Exec(ctx context.Context, conn *sql.DB) (int, error) {
+	// 1 insert for every 4 selects, taosdemo-style.
+	if atomic.AddInt64(&w.calls, 1)%5 == 0 {
+		id := atomic.AddInt64(&w.nextID, 1)
+		query := fmt.Sprintf("INSERT INTO %s (id) VALUES (%d)", w.config.TableName, id)
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+
+	id := rand.Intn(w.config.Rows)
+	query := fmt.Sprintf("SELECT id FROM %s WHERE id = %d", w.config.TableName, id)
+	var found int
+	err := conn.QueryRowContext(ctx, query).Scan(&found)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
 // RunClient executes the test queries for a single client
-func (t *TestRunner) RunClient(clientID int, duration time.Duration, resultChan chan<- ClientResult) {
+func (t *TestRunner) RunClient(clientID int, duration time.Duration, workload Workload, resultChan chan<- ClientResult) {
 	// Create a new connection for this client
 	if t.Config.Verbose {
 		fmt.Printf("Client %d: Connecting to TiDB...\n", clientID)
 	}
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?timeout=300s&autocommit=true",
-		t.Config.User, t.Config.Password, t.Config.Host, t.Config.Port, t.Config.Database)
+	clientParam := t.Config.MySQLConnectParam
+	clientParam.DialTimeout = 300 * time.Second
+	if workloadVars := workload.Vars(); len(workloadVars) > 0 {
+		merged := make(map[string]string, len(clientParam.Vars)+len(workloadVars))
+		for name, value := range clientParam.Vars {
+			merged[name] = value
+		}
+		for name, value := range workloadVars {
+			merged[name] = value
+		}
+		clientParam.Vars = merged
+	}
 
-	conn, err := sql.Open("mysql", dsn)
+	conn, err := clientParam.Connect(t.Ctx)
 	if err != nil {
 		fmt.Printf("Client %d: Failed to connect: %v\n", clientID, err)
-		resultChan <- ClientResult{0, 1, 0}
+		resultChan <- ClientResult{0, 1, 0, newLatencyHistogram()}
 		return
 	}
 	defer conn.Close()
@@ -272,20 +840,16 @@ func (t *TestRunner) RunClient(clientID int, duration time.Duration, resultChan
 	err = conn.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s LIMIT 1", t.Config.TableName)).Scan(&count)
 	if err != nil {
 		fmt.Printf("Client %d: ERROR - Cannot access test table: %v\n", clientID, err)
-		resultChan <- ClientResult{0, 1, 0}
+		resultChan <- ClientResult{0, 1, 0, newLatencyHistogram()}
 		return
 	}
 
 	if t.Config.Verbose || clientID == 0 {
 		fmt.Printf("Client %d: Table verification successful. Found %d total rows.\n", clientID, count)
-		
-		// Log query type only once at the beginning
+
+		// Log workload only once at the beginning
 		if clientID == 0 {
-			if t.Config.UseFutureTS {
-				fmt.Printf("Using queries with future timestamp (AS OF TIMESTAMP) of %d ms\n", t.Config.FutureTS)
-			} else {
-				fmt.Printf("Using regular queries (without AS OF TIMESTAMP)\n")
-			}
+			fmt.Printf("Using workload %q\n", workload.Name())
 		}
 	}
 
@@ -296,34 +860,19 @@ func (t *TestRunner) RunClient(clientID int, duration time.Duration, resultChan
 	queryCount := 0
 	errorCount := 0
 	recordsScanned := 0
+	latencies := newLatencyHistogram()
+	ctx := context.Background()
 
 	for time.Now().Before(endTime) {
-		var query string
-		
-		if t.Config.UseFutureTS {
-			// Use fixed future timestamp
-			microseconds := t.Config.FutureTS * 1000
-			futureTS := fmt.Sprintf("NOW() + INTERVAL %d MICROSECOND", microseconds)
-			query = fmt.Sprintf("SELECT COUNT(*) FROM %s AS OF TIMESTAMP %s", t.Config.TableName, futureTS)
-		} else {
-			// Regular query without future timestamp
-			query = fmt.Sprintf("SELECT COUNT(*) FROM %s", t.Config.TableName)
-		}
-
-		var recordCount int
-		err := conn.QueryRow(query).Scan(&recordCount)
+		queryStart := time.Now()
+		recordCount, err := workload.Exec(ctx, conn)
+		latencies.Record(time.Since(queryStart))
 		if err != nil {
 			errorCount++
 			if t.Config.Verbose && (errorCount == 1 || (errorCount <= 10 && errorCount%5 == 0) || errorCount%100 == 0) {
 				fmt.Printf("Client %d: Query failed (%d times): %v...\n", clientID, errorCount, err)
-				if errorCount == 1 {
-					fmt.Printf("Query was: %s\n", query)
-				}
 			} else if !t.Config.Verbose && (errorCount == 1) {
 				fmt.Printf("Client %d: Query failed (%d times): %v...\n", clientID, errorCount, err)
-				if errorCount == 1 {
-					fmt.Printf("Query was: %s\n", query)
-				}
 			}
 
 			continue
@@ -349,64 +898,173 @@ func (t *TestRunner) RunClient(clientID int, duration time.Duration, resultChan
 	fmt.Printf("Client %d completed: %d queries, %d errors, %d records\n",
 		clientID, queryCount, errorCount, recordsScanned)
 
-	resultChan <- ClientResult{queryCount, errorCount, recordsScanned}
+	resultChan <- ClientResult{queryCount, errorCount, recordsScanned, latencies}
 }
 
-// RunTest runs a test with a specific concurrency level
-func (t *TestRunner) RunTest(concurrency int) TestResult {
-	fmt.Printf("\nStarting test with concurrency %d...\n", concurrency)
-
-	// Collect counter value before test
-	var beforeCounter float64
-	var err error
-	if t.Config.CollectMetrics {
-		fmt.Println("Collecting counter value before test...")
-		beforeCounter, err = t.queryPrometheusCounter()
-		if err != nil {
-			fmt.Printf("WARNING: Failed to collect pre-test counter: %v\n", err)
-		} else {
-			fmt.Printf("Initial TSO counter value: %.0f\n", beforeCounter)
-		}
-	}
-	
+// runClosedLoop drives the test by keeping `concurrency` goroutines each
+// issuing one query at a time as fast as the server responds. This is the
+// tool's original behavior.
+func (t *TestRunner) runClosedLoop(concurrency int, workload Workload) (totalQueries, totalErrors, totalRecords int, latencies *LatencyHistogram, actualDuration float64) {
 	resultChan := make(chan ClientResult, concurrency)
 	var wg sync.WaitGroup
 
 	startTime := time.Now()
 
-	// Start client goroutines
 	for i := 0; i < concurrency; i++ {
 		wg.Add(1)
 		go func(clientID int) {
 			defer wg.Done()
-			t.RunClient(clientID, time.Duration(t.Config.Duration)*time.Second, resultChan)
+			t.RunClient(clientID, time.Duration(t.Config.Duration)*time.Second, workload, resultChan)
 		}(i)
 
-		// Progress for large concurrency values
 		if concurrency > 50 && i > 0 && i%50 == 0 {
 			fmt.Printf("Started %d of %d clients...\n", i, concurrency)
 		}
 	}
 
-	// Wait for all clients in a separate goroutine
 	go func() {
 		wg.Wait()
 		close(resultChan)
 	}()
 
-	// Collect results
-	totalQueries := 0
-	totalErrors := 0
-	totalRecords := 0
-
+	latencies = newLatencyHistogram()
 	for result := range resultChan {
 		totalQueries += result.Queries
 		totalErrors += result.Errors
 		totalRecords += result.RecordsScanned
+		latencies.Merge(result.Latencies)
+	}
+
+	actualDuration = time.Since(startTime).Seconds()
+	return
+}
+
+// runOpenLoop drives the test with requests dispatched at fixed wall-clock
+// instants computed from Config.TargetQPS, instead of waiting for the
+// previous request on the same "client" to complete. maxInflight bounds the
+// number of requests in flight at once (the concurrency level is used as the
+// default when Config.MaxInflight is unset), via a shared *sql.DB connection
+// pool rather than per-client connections.
+//
+// Crucially, latency for a request is measured from the time it was
+// *scheduled* to be sent, not from when it actually started — a worker that
+// falls behind schedule still reports the growing wait as latency, which is
+// what avoids coordinated omission in the tail.
+func (t *TestRunner) runOpenLoop(concurrency int, workload Workload) (totalQueries, totalErrors, totalRecords int, serviceLatencies, scheduledLatencies *LatencyHistogram, actualDuration float64) {
+	maxInflight := t.Config.MaxInflight
+	if maxInflight <= 0 {
+		maxInflight = concurrency
+	}
+
+	serviceLatencies = newLatencyHistogram()
+	scheduledLatencies = newLatencyHistogram()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxInflight)
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	meanInterval := time.Duration(float64(time.Second) / t.Config.TargetQPS)
+
+	startTime := time.Now()
+	endTime := startTime.Add(time.Duration(t.Config.Duration) * time.Second)
+
+	nextDispatch := startTime
+	for nextDispatch.Before(endTime) {
+		scheduledAt := nextDispatch
+
+		if wait := time.Until(scheduledAt); wait > 0 {
+			time.Sleep(wait)
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(scheduledAt time.Time) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			sendStart := time.Now()
+			records, err := workload.Exec(context.Background(), t.DB)
+			now := time.Now()
+
+			mu.Lock()
+			defer mu.Unlock()
+			scheduledLatencies.Record(now.Sub(scheduledAt))
+			serviceLatencies.Record(now.Sub(sendStart))
+			if err != nil {
+				totalErrors++
+				return
+			}
+			totalQueries++
+			totalRecords += records
+		}(scheduledAt)
+
+		var interval time.Duration
+		if t.Config.ArrivalDist == "uniform" {
+			interval = meanInterval
+		} else {
+			// Poisson arrivals: inter-arrival times are exponentially distributed.
+			interval = time.Duration(-math.Log(1-rng.Float64()) * float64(meanInterval))
+		}
+		nextDispatch = nextDispatch.Add(interval)
+	}
+
+	wg.Wait()
+	actualDuration = time.Since(startTime).Seconds()
+	return
+}
+
+// RunTest runs a test with a specific concurrency level
+func (t *TestRunner) RunTest(concurrency int) TestResult {
+	fmt.Printf("\nStarting test with concurrency %d...\n", concurrency)
+
+	checkpointKey := configCheckpointKey(t.Config)
+	if t.Checkpoint != nil && !t.Config.Restart {
+		if cached, ok, err := t.Checkpoint.LoadResult(checkpointKey, concurrency); err != nil {
+			fmt.Printf("WARNING: failed to read checkpoint: %v\n", err)
+		} else if ok {
+			fmt.Printf("Checkpoint: concurrency %d already recorded for this configuration, skipping\n", concurrency)
+			t.TestResults[concurrency] = cached
+			return cached
+		}
+	}
+
+	// Collect counter value before test
+	var beforeCounter float64
+	var err error
+	if t.Config.CollectMetrics {
+		fmt.Println("Collecting counter value before test...")
+		beforeCounter, err = t.queryPrometheusCounter()
+		if err != nil {
+			fmt.Printf("WARNING: Failed to collect pre-test counter: %v\n", err)
+		} else {
+			fmt.Printf("Initial TSO counter value: %.0f\n", beforeCounter)
+		}
+	}
+	
+	workload, err := newWorkload(t.Config.Workload, t.Config)
+	if err != nil {
+		log.Fatalf("invalid workload: %v", err)
 	}
+	if err := workload.Prepare(t.DB); err != nil {
+		log.Fatalf("failed to prepare workload %q: %v", workload.Name(), err)
+	}
+
+	var (
+		totalQueries, totalErrors, totalRecords int
+		latencies, scheduledLatencies           *LatencyHistogram
+		actualDuration                          float64
+	)
 
-	endTime := time.Now()
-	actualDuration := endTime.Sub(startTime).Seconds()
+	testStartTime := time.Now()
+	if t.Config.Mode == "open" {
+		totalQueries, totalErrors, totalRecords, latencies, scheduledLatencies, actualDuration =
+			t.runOpenLoop(concurrency, workload)
+	} else {
+		totalQueries, totalErrors, totalRecords, latencies, actualDuration = t.runClosedLoop(concurrency, workload)
+		scheduledLatencies = newLatencyHistogram()
+	}
+	testEndTime := time.Now()
 
 	totalAttempts := totalQueries + totalErrors
 
@@ -435,25 +1093,97 @@ func (t *TestRunner) RunTest(concurrency int) TestResult {
 		}
 	}
 
-	// Create result
-	result := TestResult{
-		Concurrency:         concurrency,
-		Duration:            actualDuration,
-		TotalAttempts:       totalAttempts,
-		TotalQueries:        totalQueries,
-		TotalErrors:         totalErrors,
-		TotalRecordsScanned: totalRecords,
-		AttemptsPerSec:      attemptsPerSec,
-		SuccessfulQPS:       successfulQPS,
+	// Collect the broader Prometheus metric set over the exact test window
+	var metricSeries []MetricSeriesResult
+	if t.Config.CollectMetrics {
+		if t.Metrics == nil {
+			collector, err := NewMetricsCollector(t.Config.PrometheusAddr, t.Config.PrometheusPort, t.Config.MetricsConfigPath)
+			if err != nil {
+				fmt.Printf("WARNING: Failed to build metrics collector: %v\n", err)
+			}
+			t.Metrics = collector
+		}
+		if t.Metrics != nil {
+			step := time.Duration(t.Config.MetricsStep) * time.Second
+			if step <= 0 {
+				step = 15 * time.Second
+			}
+			metricSeries = t.Metrics.CollectRange(t.Ctx, testStartTime, testEndTime, step)
+		}
+	}
+
+	// Read PD-side TSO handling latency quantiles over the test window, so
+	// reports can show whether future-TS reduces tail latency and not just
+	// request count.
+	var tsoLatencyP50, tsoLatencyP95, tsoLatencyP99 time.Duration
+	if t.Config.CollectMetrics {
+		window := testEndTime.Sub(testStartTime)
+		if window < 30*time.Second {
+			window = 30 * time.Second
+		}
+		tsoLatencyP50, tsoLatencyP95, tsoLatencyP99 = t.tsoLatencyQuantiles(window, testEndTime)
+	}
+
+	// Create result
+	result := TestResult{
+		Concurrency:         concurrency,
+		Duration:            actualDuration,
+		TotalAttempts:       totalAttempts,
+		TotalQueries:        totalQueries,
+		TotalErrors:         totalErrors,
+		TotalRecordsScanned: totalRecords,
+		AttemptsPerSec:      attemptsPerSec,
+		SuccessfulQPS:       successfulQPS,
 		RecordsPerSec:       recordsPerSec,
 		ErrorRate:           errorRate,
 		SplitRegions:        t.Config.SplitRegions,
 		UseFutureTS:         t.Config.UseFutureTS,
 		TSORequests:         tsoRequestCount,
+		TSOLatencyP50Ms:     tsoLatencyP50.Seconds() * 1000,
+		TSOLatencyP95Ms:     tsoLatencyP95.Seconds() * 1000,
+		TSOLatencyP99Ms:     tsoLatencyP99.Seconds() * 1000,
+		LatencyP50Ms:        latencies.Percentile(50).Seconds() * 1000,
+		LatencyP90Ms:        latencies.Percentile(90).Seconds() * 1000,
+		LatencyP95Ms:        latencies.Percentile(95).Seconds() * 1000,
+		LatencyP99Ms:        latencies.Percentile(99).Seconds() * 1000,
+		LatencyP999Ms:       latencies.Percentile(99.9).Seconds() * 1000,
+		LatencyMaxMs:        latencies.Max().Seconds() * 1000,
+		LatencyBuckets:      latencies.Buckets(),
+		Workload:            workload.Name(),
+		ScheduledLatencyP50Ms: scheduledLatencies.Percentile(50).Seconds() * 1000,
+		ScheduledLatencyP95Ms: scheduledLatencies.Percentile(95).Seconds() * 1000,
+		ScheduledLatencyP99Ms: scheduledLatencies.Percentile(99).Seconds() * 1000,
+		ScheduledLatencyMaxMs: scheduledLatencies.Max().Seconds() * 1000,
+		MetricSeries:        metricSeries,
 	}
 
 	t.TestResults[concurrency] = result
 
+	if t.Checkpoint != nil {
+		if err := t.Checkpoint.SaveResult(checkpointKey, concurrency, result); err != nil {
+			fmt.Printf("WARNING: failed to persist result checkpoint: %v\n", err)
+		}
+	}
+
+	if hasFormat(t.Config.OutputFormats, "jsonl") {
+		jsonlPath := fmt.Sprintf("%s_results.jsonl", t.Config.TableName)
+		if err := appendResultJSONL(jsonlPath, result); err != nil {
+			fmt.Printf("WARNING: Failed to append JSONL result: %v\n", err)
+		}
+	}
+	if hasFormat(t.Config.OutputFormats, "pushgateway") {
+		if t.Config.PushgatewayURL == "" {
+			fmt.Println("WARNING: output-format=pushgateway requires -pushgateway-url")
+		} else if err := pushResultToGateway(t.Config.PushgatewayURL, result); err != nil {
+			fmt.Printf("WARNING: Failed to push result to Pushgateway: %v\n", err)
+		}
+	}
+	if t.Config.RemoteWriteURL != "" {
+		if err := pushResultRemoteWrite(t.Config.RemoteWriteURL, t.Config.RemoteWriteV2, result, t.Config, t.Config.RunID); err != nil {
+			fmt.Printf("WARNING: Failed to push result via remote_write: %v\n", err)
+		}
+	}
+
 	// Print results
 	fmt.Printf("Test results for concurrency %d:\n", concurrency)
 	fmt.Printf("  Actual test duration: %.2f seconds\n", actualDuration)
@@ -466,6 +1196,22 @@ func (t *TestRunner) RunTest(concurrency int) TestResult {
 		fmt.Printf("  Successful QPS: %.2f\n", successfulQPS)
 	}
 	fmt.Printf("  Error rate: %.2f%%\n", errorRate*100)
+	fmt.Printf("  Latency (ms) p50=%.2f p90=%.2f p95=%.2f p99=%.2f p999=%.2f max=%.2f\n",
+		result.LatencyP50Ms, result.LatencyP90Ms, result.LatencyP95Ms,
+		result.LatencyP99Ms, result.LatencyP999Ms, result.LatencyMaxMs)
+	if t.Config.Mode == "open" {
+		fmt.Printf("  Scheduled latency (ms) p50=%.2f p95=%.2f p99=%.2f max=%.2f\n",
+			result.ScheduledLatencyP50Ms, result.ScheduledLatencyP95Ms,
+			result.ScheduledLatencyP99Ms, result.ScheduledLatencyMaxMs)
+	}
+	if t.Config.CollectMetrics {
+		fmt.Printf("  PD TSO handling latency (ms) p50=%.2f p95=%.2f p99=%.2f\n",
+			result.TSOLatencyP50Ms, result.TSOLatencyP95Ms, result.TSOLatencyP99Ms)
+	}
+	for _, series := range result.MetricSeries {
+		fmt.Printf("  %-35s min=%.4f avg=%.4f max=%.4f p99=%.4f\n",
+			series.Name, series.Min, series.Avg, series.Max, series.P99)
+	}
 
 	// Cooldown
 	fmt.Printf("Waiting for system cooldown, %d seconds...\n", t.Config.Cooldown)
@@ -498,7 +1244,8 @@ func (t *TestRunner) GenerateReport() {
 	report.WriteString(fmt.Sprintf("Row count: %d\n", t.Config.Rows))
 	report.WriteString(fmt.Sprintf("Test duration per concurrency: %d seconds\n", t.Config.Duration))
 	report.WriteString(fmt.Sprintf("Split regions: %v (count: %d)\n", t.Config.SplitRegions, t.Config.RegionCount))
-	report.WriteString(fmt.Sprintf("Use future timestamp: %v (%d milliseconds)\n\n", t.Config.UseFutureTS, t.Config.FutureTS))
+	report.WriteString(fmt.Sprintf("Use future timestamp: %v (%d milliseconds)\n", t.Config.UseFutureTS, t.Config.FutureTS))
+	report.WriteString(fmt.Sprintf("Workload: %s\n\n", t.Config.Workload))
 
 	// Headers
 	report.WriteString("Test Results:\n")
@@ -527,11 +1274,58 @@ func (t *TestRunner) GenerateReport() {
 	}
 	report.WriteString("--------------------------------------------------------------------------------\n")
 
+	// Latency percentiles
+	report.WriteString("\nLatency Percentiles (ms):\n")
+	report.WriteString("--------------------------------------------------------------------------------\n")
+	report.WriteString(fmt.Sprintf("%-10s %-10s %-10s %-10s %-10s %-10s %-10s\n",
+		"Concurr.", "p50", "p90", "p95", "p99", "p999", "max"))
+	report.WriteString("--------------------------------------------------------------------------------\n")
+	for _, concurrency := range levels {
+		result := t.TestResults[concurrency]
+		report.WriteString(fmt.Sprintf("%-10d %-10.2f %-10.2f %-10.2f %-10.2f %-10.2f %-10.2f\n",
+			concurrency,
+			result.LatencyP50Ms, result.LatencyP90Ms, result.LatencyP95Ms,
+			result.LatencyP99Ms, result.LatencyP999Ms, result.LatencyMaxMs))
+	}
+	report.WriteString("--------------------------------------------------------------------------------\n")
+
+	// Scheduled latency measures from the intended dispatch time rather than
+	// the actual send time, so client-side backlog under contention shows up
+	// here instead of being hidden by coordinated omission. Only populated
+	// in open-loop mode.
+	if t.Config.Mode == "open" {
+		report.WriteString("\nScheduled Latency Percentiles (ms):\n")
+		report.WriteString("--------------------------------------------------------------------------------\n")
+		report.WriteString(fmt.Sprintf("%-10s %-10s %-10s %-10s\n",
+			"Concurr.", "p50", "p95", "p99"))
+		report.WriteString("--------------------------------------------------------------------------------\n")
+		for _, concurrency := range levels {
+			result := t.TestResults[concurrency]
+			report.WriteString(fmt.Sprintf("%-10d %-10.2f %-10.2f %-10.2f\n",
+				concurrency,
+				result.ScheduledLatencyP50Ms, result.ScheduledLatencyP95Ms, result.ScheduledLatencyP99Ms))
+		}
+		report.WriteString("--------------------------------------------------------------------------------\n")
+	}
+
 	// If we have metrics data, generate metrics report
 	if t.Config.CollectMetrics {
+		// PD-side TSO handling latency, so readers can see whether future-TS
+		// affects PD's tail latency and not just the request rate.
+		report.WriteString("\nPD TSO Handling Latency (ms):\n")
+		report.WriteString("--------------------------------------------------------------------------------\n")
+		report.WriteString(fmt.Sprintf("%-10s %-10s %-10s %-10s\n", "Concurr.", "p50", "p95", "p99"))
+		report.WriteString("--------------------------------------------------------------------------------\n")
+		for _, level := range levels {
+			result := t.TestResults[level]
+			report.WriteString(fmt.Sprintf("%-10d %-10.2f %-10.2f %-10.2f\n",
+				level, result.TSOLatencyP50Ms, result.TSOLatencyP95Ms, result.TSOLatencyP99Ms))
+		}
+		report.WriteString("--------------------------------------------------------------------------------\n")
+
 		report.WriteString("\nPrometheus Metrics Summary:\n")
 		report.WriteString("--------------------------------------------------------------------------------\n")
-		
+
 		// Print TSO request information for each concurrency
 		for _, level := range levels {
 			result := t.TestResults[level]
@@ -575,11 +1369,9 @@ func (t *TestRunner) GenerateReport() {
 	// Print to console
 	fmt.Print(report.String())
 
-	// Save test results as JSON
-	jsonData, err := json.MarshalIndent(t.TestResults, "", "  ")
-	if err != nil {
-		fmt.Printf("Failed to serialize test results: %v\n", err)
-		return
+	formats := t.Config.OutputFormats
+	if len(formats) == 0 {
+		formats = []string{"text", "json"}
 	}
 
 	// Create a filename that includes test configuration
@@ -587,34 +1379,96 @@ func (t *TestRunner) GenerateReport() {
 	if t.Config.SplitRegions {
 		splitStatus = fmt.Sprintf("split%d", t.Config.RegionCount)
 	}
-	
+
 	tsStatus := "regular"
 	if t.Config.UseFutureTS {
 		tsStatus = fmt.Sprintf("futurets%d", t.Config.FutureTS)
 	}
-	
+
 	// Generate timestamp for files
 	timestamp := time.Now().Format("20060102_150405")
 	jsonFilename := fmt.Sprintf("tidb_test_%s_%s_%s.json", splitStatus, tsStatus, timestamp)
-	
-	// Save the report text to a file
 	reportFilename := fmt.Sprintf("tidb_test_%s_%s_%s_report.txt", splitStatus, tsStatus, timestamp)
-	
-	// Write JSON results
-	err = os.WriteFile(jsonFilename, jsonData, 0644)
-	if err != nil {
-		fmt.Printf("Failed to save test results: %v\n", err)
-		return
+	csvFilename := fmt.Sprintf("tidb_test_%s_%s_%s.csv", splitStatus, tsStatus, timestamp)
+
+	if hasFormat(formats, "json") {
+		jsonData, err := json.MarshalIndent(t.TestResults, "", "  ")
+		if err != nil {
+			fmt.Printf("Failed to serialize test results: %v\n", err)
+			return
+		}
+		if err := os.WriteFile(jsonFilename, jsonData, 0644); err != nil {
+			fmt.Printf("Failed to save test results: %v\n", err)
+			return
+		}
+		fmt.Printf("Test results saved to %s\n", jsonFilename)
 	}
-	
-	// Write text report
-	err = os.WriteFile(reportFilename, []byte(report.String()), 0644)
-	if err != nil {
-		fmt.Printf("Failed to save test report: %v\n", err)
-		return
+
+	if hasFormat(formats, "text") {
+		if err := os.WriteFile(reportFilename, []byte(report.String()), 0644); err != nil {
+			fmt.Printf("Failed to save test report: %v\n", err)
+			return
+		}
+		fmt.Printf("Test report saved to %s\n", reportFilename)
+	}
+
+	if hasFormat(formats, "csv") {
+		var results []TestResult
+		for _, level := range levels {
+			results = append(results, t.TestResults[level])
+		}
+		if err := writeResultsCSV(csvFilename, results); err != nil {
+			fmt.Printf("Failed to save CSV results: %v\n", err)
+			return
+		}
+		fmt.Printf("Test results saved to %s\n", csvFilename)
+	}
+}
+
+// configLabel renders every axis a "bench sweep" TestPlan can vary a
+// TestConfig across — workload, split/region count, future-ts/ms, rows,
+// concurrency levels, and session vars — into one human-readable string.
+// Two configs that only share split/future-ts but differ elsewhere must
+// still produce distinct labels, or comparison-report columns collapse
+// into indistinguishable duplicates.
+func configLabel(config TestConfig) string {
+	splitStatus := "No Split"
+	if config.SplitRegions {
+		splitStatus = fmt.Sprintf("Split(%d)", config.RegionCount)
+	}
+
+	tsStatus := "Regular"
+	if config.UseFutureTS {
+		tsStatus = fmt.Sprintf("Future(%d)", config.FutureTS)
+	}
+
+	parts := []string{config.Workload, splitStatus, tsStatus, fmt.Sprintf("rows=%d", config.Rows)}
+	if len(config.ConcurrencyLevels) > 0 {
+		parts = append(parts, fmt.Sprintf("conc=%v", config.ConcurrencyLevels))
+	}
+	if len(config.Vars) > 0 {
+		keys := make([]string, 0, len(config.Vars))
+		for name := range config.Vars {
+			keys = append(keys, name)
+		}
+		sort.Strings(keys)
+		varParts := make([]string, 0, len(keys))
+		for _, name := range keys {
+			varParts = append(varParts, fmt.Sprintf("%s=%s", name, config.Vars[name]))
+		}
+		parts = append(parts, strings.Join(varParts, "&"))
 	}
 
-	fmt.Printf("Test results saved to %s and %s\n", jsonFilename, reportFilename)
+	return strings.Join(parts, ",")
+}
+
+// configKey is configLabel rendered as a JSON-map-safe identifier: same
+// axes, but with the separators that configLabel uses for readability
+// ("," "=" "&") swapped out so it's unambiguous as a map key.
+func configKey(config TestConfig) string {
+	label := configLabel(config)
+	replacer := strings.NewReplacer(",", "_", "=", "-", "&", "+", "(", "", ")", "")
+	return replacer.Replace(label)
 }
 
 // GenerateComparisonReport generates a comparison report across all test combinations
@@ -653,18 +1507,7 @@ func (g *TestRunnerGroup) GenerateComparisonReport() {
 	// Print header
 	report.WriteString(fmt.Sprintf("%-10s ", "Concurr."))
 	for _, runner := range g.Runners {
-		config := runner.Config
-		splitStatus := "No Split"
-		if config.SplitRegions {
-			splitStatus = fmt.Sprintf("Split(%d)", config.RegionCount)
-		}
-		
-		tsStatus := "Regular"
-		if config.UseFutureTS {
-			tsStatus = fmt.Sprintf("Future(%d)", config.FutureTS)
-		}
-		
-		title := fmt.Sprintf("%s,%s", splitStatus, tsStatus)
+		title := configLabel(runner.Config)
 		report.WriteString(fmt.Sprintf("%-20s ", title))
 	}
 	report.WriteString("\n")
@@ -693,18 +1536,7 @@ func (g *TestRunnerGroup) GenerateComparisonReport() {
 	// Print header
 	report.WriteString(fmt.Sprintf("%-10s ", "Concurr."))
 	for _, runner := range g.Runners {
-		config := runner.Config
-		splitStatus := "No Split"
-		if config.SplitRegions {
-			splitStatus = fmt.Sprintf("Split(%d)", config.RegionCount)
-		}
-		
-		tsStatus := "Regular"
-		if config.UseFutureTS {
-			tsStatus = fmt.Sprintf("Future(%d)", config.FutureTS)
-		}
-		
-		title := fmt.Sprintf("%s,%s", splitStatus, tsStatus)
+		title := configLabel(runner.Config)
 		report.WriteString(fmt.Sprintf("%-20s ", title))
 	}
 	report.WriteString("\n")
@@ -733,18 +1565,7 @@ func (g *TestRunnerGroup) GenerateComparisonReport() {
 	// Print header
 	report.WriteString(fmt.Sprintf("%-10s ", "Concurr."))
 	for _, runner := range g.Runners {
-		config := runner.Config
-		splitStatus := "No Split"
-		if config.SplitRegions {
-			splitStatus = fmt.Sprintf("Split(%d)", config.RegionCount)
-		}
-		
-		tsStatus := "Regular"
-		if config.UseFutureTS {
-			tsStatus = fmt.Sprintf("Future(%d)", config.FutureTS)
-		}
-		
-		title := fmt.Sprintf("%s,%s", splitStatus, tsStatus)
+		title := configLabel(runner.Config)
 		report.WriteString(fmt.Sprintf("%-20s ", title))
 	}
 	report.WriteString("\n")
@@ -766,6 +1587,36 @@ func (g *TestRunnerGroup) GenerateComparisonReport() {
 	}
 	report.WriteString("--------------------------------------------------------------------------------\n")
 
+	// Create a table comparing tail latency (p50/p95/p99 in ms)
+	report.WriteString("\n4. Latency Percentile Comparison (p50/p95/p99 ms):\n")
+	report.WriteString("--------------------------------------------------------------------------------\n")
+
+	// Print header
+	report.WriteString(fmt.Sprintf("%-10s ", "Concurr."))
+	for _, runner := range g.Runners {
+		title := configLabel(runner.Config)
+		report.WriteString(fmt.Sprintf("%-30s ", title))
+	}
+	report.WriteString("\n")
+	report.WriteString("--------------------------------------------------------------------------------\n")
+
+	// Print data rows
+	for _, level := range levels {
+		report.WriteString(fmt.Sprintf("%-10d ", level))
+
+		for _, runner := range g.Runners {
+			result, ok := runner.TestResults[level]
+			if ok {
+				cell := fmt.Sprintf("%.2f/%.2f/%.2f", result.LatencyP50Ms, result.LatencyP95Ms, result.LatencyP99Ms)
+				report.WriteString(fmt.Sprintf("%-30s ", cell))
+			} else {
+				report.WriteString(fmt.Sprintf("%-30s ", "N/A"))
+			}
+		}
+		report.WriteString("\n")
+	}
+	report.WriteString("--------------------------------------------------------------------------------\n")
+
 	// Calculate average metrics for each runner
 	hasMetrics := false
 	for _, runner := range g.Runners {
@@ -776,8 +1627,37 @@ func (g *TestRunnerGroup) GenerateComparisonReport() {
 	}
 	
 	if hasMetrics {
+		// PD-side TSO handling latency comparison, read from the histogram
+		// rather than measured client-side, so it's directly comparable
+		// across configurations even under different client load.
+		report.WriteString("\n5. PD TSO Handling Latency Comparison (p50/p95/p99 ms):\n")
+		report.WriteString("--------------------------------------------------------------------------------\n")
+
+		report.WriteString(fmt.Sprintf("%-10s ", "Concurr."))
+		for _, runner := range g.Runners {
+			title := configLabel(runner.Config)
+			report.WriteString(fmt.Sprintf("%-30s ", title))
+		}
+		report.WriteString("\n")
+		report.WriteString("--------------------------------------------------------------------------------\n")
+
+		for _, level := range levels {
+			report.WriteString(fmt.Sprintf("%-10d ", level))
+			for _, runner := range g.Runners {
+				result, ok := runner.TestResults[level]
+				if ok {
+					cell := fmt.Sprintf("%.2f/%.2f/%.2f", result.TSOLatencyP50Ms, result.TSOLatencyP95Ms, result.TSOLatencyP99Ms)
+					report.WriteString(fmt.Sprintf("%-30s ", cell))
+				} else {
+					report.WriteString(fmt.Sprintf("%-30s ", "N/A"))
+				}
+			}
+			report.WriteString("\n")
+		}
+		report.WriteString("--------------------------------------------------------------------------------\n")
+
 		// Print summary of TSO request comparison
-		report.WriteString("\n4. TSO Requests Analysis:\n")
+		report.WriteString("\n6. TSO Requests Analysis:\n")
 		report.WriteString("--------------------------------------------------------------------------------\n")
 		
 		// Calculate average TSO requests for each configuration
@@ -785,20 +1665,8 @@ func (g *TestRunnerGroup) GenerateComparisonReport() {
 		report.WriteString("--------------------------------------------------------------------------------\n")
 		
 		for _, runner := range g.Runners {
-			config := runner.Config
-			
-			splitStatus := "No Split"
-			if config.SplitRegions {
-				splitStatus = fmt.Sprintf("Split(%d)", config.RegionCount)
-			}
-			
-			tsStatus := "Regular"
-			if config.UseFutureTS {
-				tsStatus = fmt.Sprintf("Future(%d)", config.FutureTS)
-			}
-			
-			title := fmt.Sprintf("%s + %s", splitStatus, tsStatus)
-			
+			title := configLabel(runner.Config)
+
 			// Calculate averages
 			var totalTSO, totalDuration float64
 			var count int
@@ -886,35 +1754,77 @@ func (g *TestRunnerGroup) GenerateComparisonReport() {
 	
 	// Save report to file
 	timestamp := time.Now().Format("20060102_150405")
-	filename := fmt.Sprintf("tidb_test_comparison_%s.txt", timestamp)
-	
-	// Save all test results as a single JSON file
-	resultsMap := make(map[string]map[int]TestResult)
-	for _, runner := range g.Runners {
-		config := runner.Config
-		configKey := fmt.Sprintf("split_%v_futurets_%v", config.SplitRegions, config.UseFutureTS)
-		resultsMap[configKey] = runner.TestResults
+
+	formats := []string{"text", "json"}
+	if len(g.Runners) > 0 && len(g.Runners[0].Config.OutputFormats) > 0 {
+		formats = g.Runners[0].Config.OutputFormats
 	}
-	
-	jsonData, err := json.MarshalIndent(resultsMap, "", "  ")
-	if err != nil {
-		fmt.Printf("Failed to serialize comparison results: %v\n", err)
-	} else {
-		jsonFilename := fmt.Sprintf("tidb_test_comparison_%s.json", timestamp)
-		if err = os.WriteFile(jsonFilename, jsonData, 0644); err != nil {
-			fmt.Printf("Failed to save comparison results: %v\n", err)
+
+	if hasFormat(formats, "json") {
+		// Save all test results as a single JSON file
+		resultsMap := make(map[string]map[int]TestResult)
+		for _, runner := range g.Runners {
+			key := configKey(runner.Config)
+			resultsMap[key] = runner.TestResults
+		}
+
+		jsonData, err := json.MarshalIndent(resultsMap, "", "  ")
+		if err != nil {
+			fmt.Printf("Failed to serialize comparison results: %v\n", err)
 		} else {
-			fmt.Printf("Comparison results saved to %s\n", jsonFilename)
+			jsonFilename := fmt.Sprintf("tidb_test_comparison_%s.json", timestamp)
+			if err = os.WriteFile(jsonFilename, jsonData, 0644); err != nil {
+				fmt.Printf("Failed to save comparison results: %v\n", err)
+			} else {
+				fmt.Printf("Comparison results saved to %s\n", jsonFilename)
+			}
 		}
 	}
-	
-	// Write text report
-	if err = os.WriteFile(filename, []byte(report.String()), 0644); err != nil {
-		fmt.Printf("Failed to save comparison report: %v\n", err)
-		return
+
+	if hasFormat(formats, "text") {
+		filename := fmt.Sprintf("tidb_test_comparison_%s.txt", timestamp)
+		if err := os.WriteFile(filename, []byte(report.String()), 0644); err != nil {
+			fmt.Printf("Failed to save comparison report: %v\n", err)
+		} else {
+			fmt.Printf("Comparison report saved to %s\n", filename)
+		}
 	}
-	
-	fmt.Printf("Comparison report saved to %s\n", filename)
+
+	if hasFormat(formats, "csv") {
+		var allResults []TestResult
+		for _, runner := range g.Runners {
+			for _, level := range getSortedConcurrencyLevels(runner.TestResults) {
+				allResults = append(allResults, runner.TestResults[level])
+			}
+		}
+		csvFilename := fmt.Sprintf("tidb_test_comparison_%s.csv", timestamp)
+		if err := writeResultsCSV(csvFilename, allResults); err != nil {
+			fmt.Printf("Failed to save comparison CSV: %v\n", err)
+		} else {
+			fmt.Printf("Comparison results saved to %s\n", csvFilename)
+		}
+	}
+
+	if hasFormat(formats, "markdown") {
+		mdFilename := fmt.Sprintf("tidb_test_comparison_%s.md", timestamp)
+		md := "```\n" + report.String() + "```\n"
+		if err := os.WriteFile(mdFilename, []byte(md), 0644); err != nil {
+			fmt.Printf("Failed to save comparison markdown: %v\n", err)
+		} else {
+			fmt.Printf("Comparison markdown saved to %s\n", mdFilename)
+		}
+	}
+}
+
+// getSortedConcurrencyLevels returns the concurrency levels present in a
+// TestResults map, sorted ascending, so CSV/report output is deterministic.
+func getSortedConcurrencyLevels(results map[int]TestResult) []int {
+	levels := make([]int, 0, len(results))
+	for level := range results {
+		levels = append(levels, level)
+	}
+	sort.Ints(levels)
+	return levels
 }
 
 // parseConcurrencyLevels parses the concurrency levels from a string
@@ -944,173 +1854,1162 @@ func parseConcurrencyLevels(s string) ([]int, error) {
 	return result, nil
 }
 
-// queryPrometheusCounter queries the Prometheus server and returns the raw counter value
-func (t *TestRunner) queryPrometheusCounter() (float64, error) {
-	if !t.Config.CollectMetrics {
-		return 0, nil
+// parseSessionVars parses a comma-separated "name=value" list into the map
+// MySQLConnectParam.Vars expects. An empty string yields a nil map (no
+// session variables applied).
+func parseSessionVars(s string) (map[string]string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	vars := make(map[string]string)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid session variable %q (want name=value)", part)
+		}
+		vars[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
 	}
+	return vars, nil
+}
 
-	// Check if Prometheus address is provided
-	if t.Config.PrometheusAddr == "" {
-		return 0, fmt.Errorf("Prometheus address not provided")
+var validOutputFormats = map[string]bool{
+	"text": true, "json": true, "jsonl": true, "csv": true, "pushgateway": true, "markdown": true,
+}
+
+// parseOutputFormats parses a comma-separated -output-format flag value.
+func parseOutputFormats(s string) ([]string, error) {
+	var formats []string
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !validOutputFormats[p] {
+			return nil, fmt.Errorf("unknown output format %q", p)
+		}
+		formats = append(formats, p)
+	}
+	if len(formats) == 0 {
+		return []string{"text", "json"}, nil
 	}
+	return formats, nil
+}
 
-	// Query the raw counter value directly
-	query := `sum(pd_client_request_handle_requests_duration_seconds_count{type="tso"})`
+func hasFormat(formats []string, name string) bool {
+	for _, f := range formats {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
 
-	// Build Prometheus API query URL
-	queryURL := fmt.Sprintf("http://%s:%d/api/v1/query?query=%s",
-		t.Config.PrometheusAddr, t.Config.PrometheusPort, url.QueryEscape(query))
+// resultCSVHeader/resultCSVRow format a TestResult as one CSV row, so
+// (config, concurrency, workload) combinations can be loaded directly into
+// pandas/Excel.
+func resultCSVHeader() []string {
+	return []string{
+		"concurrency", "split_regions", "use_future_ts", "workload",
+		"duration_sec", "total_attempts", "total_queries", "total_errors",
+		"attempts_per_sec", "successful_qps", "records_per_sec", "error_rate_pct",
+		"tso_requests", "tso_latency_p50_ms", "tso_latency_p95_ms", "tso_latency_p99_ms",
+		"latency_p50_ms", "latency_p95_ms", "latency_p99_ms", "latency_max_ms",
+	}
+}
 
-	// Make HTTP request
-	resp, err := http.Get(queryURL)
-	if err != nil {
-		return 0, fmt.Errorf("failed to query Prometheus: %v", err)
+func resultCSVRow(result TestResult) []string {
+	return []string{
+		strconv.Itoa(result.Concurrency),
+		strconv.FormatBool(result.SplitRegions),
+		strconv.FormatBool(result.UseFutureTS),
+		result.Workload,
+		fmt.Sprintf("%.2f", result.Duration),
+		strconv.Itoa(result.TotalAttempts),
+		strconv.Itoa(result.TotalQueries),
+		strconv.Itoa(result.TotalErrors),
+		fmt.Sprintf("%.2f", result.AttemptsPerSec),
+		fmt.Sprintf("%.2f", result.SuccessfulQPS),
+		fmt.Sprintf("%.2f", result.RecordsPerSec),
+		fmt.Sprintf("%.2f", result.ErrorRate*100),
+		fmt.Sprintf("%.0f", result.TSORequests),
+		fmt.Sprintf("%.2f", result.TSOLatencyP50Ms),
+		fmt.Sprintf("%.2f", result.TSOLatencyP95Ms),
+		fmt.Sprintf("%.2f", result.TSOLatencyP99Ms),
+		fmt.Sprintf("%.2f", result.LatencyP50Ms),
+		fmt.Sprintf("%.2f", result.LatencyP95Ms),
+		fmt.Sprintf("%.2f", result.LatencyP99Ms),
+		fmt.Sprintf("%.2f", result.LatencyMaxMs),
 	}
-	defer resp.Body.Close()
+}
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
+// writeResultsCSV writes one row per result to path, overwriting any
+// existing file.
+func writeResultsCSV(path string, results []TestResult) error {
+	f, err := os.Create(path)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read Prometheus response: %v", err)
+		return err
 	}
+	defer f.Close()
 
-	// Parse JSON response
-	var promResp struct {
-		Status string `json:"status"`
-		Data   struct {
-			ResultType string `json:"resultType"`
-			Result     []struct {
-				Metric map[string]string `json:"metric"`
-				Value  []interface{}     `json:"value"`
-			} `json:"result"`
-		} `json:"data"`
-	}
+	w := csv.NewWriter(f)
+	defer w.Flush()
 
-	if err := json.Unmarshal(body, &promResp); err != nil {
-		return 0, fmt.Errorf("failed to parse Prometheus response: %v", err)
+	if err := w.Write(resultCSVHeader()); err != nil {
+		return err
 	}
-
-	// Check if response is successful
-	if promResp.Status != "success" {
-		return 0, fmt.Errorf("Prometheus query failed with status: %s", promResp.Status)
+	for _, result := range results {
+		if err := w.Write(resultCSVRow(result)); err != nil {
+			return err
+		}
 	}
+	return w.Error()
+}
 
-	// Extract counter value
-	if len(promResp.Data.Result) == 0 {
-		return 0, fmt.Errorf("no results returned for counter query")
+// appendResultJSONL appends one JSON-encoded result as a line to path, so a
+// long sweep is analyzable before it finishes.
+func appendResultJSONL(path string, result TestResult) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
 	}
+	defer f.Close()
 
-	// Extract timestamp and value
-	valueStr := promResp.Data.Result[0].Value[1].(string)
-	value, err := strconv.ParseFloat(valueStr, 64)
+	data, err := json.Marshal(result)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse metric value: %v", err)
+		return err
 	}
-
-	return value, nil
+	_, err = f.Write(append(data, '\n'))
+	return err
 }
 
-func main() {
-	// Parse command line arguments
-	host := flag.String("host", "127.0.0.1", "TiDB host")
-	port := flag.Int("port", 4000, "TiDB port")
-	user := flag.String("user", "root", "TiDB username")
-	password := flag.String("password", "", "TiDB password")
-	database := flag.String("database", "test", "Database name")
-	tableName := flag.String("table-name", "future_ts_test", "Test table name")
-	rows := flag.Int("rows", 1000000, "Number of rows in test table")
-	regionCount := flag.Int("region-count", 1000, "Number of regions to split table into")
-	duration := flag.Int("duration", 60, "Duration of each test (seconds)")
-	concurrencyStr := flag.String("concurrency", "16", "Comma-separated list of concurrency levels to test")
-	futureTS := flag.Int("future-ts", 1000, "Fixed future timestamp in milliseconds")
-	cooldown := flag.Int("cooldown", 60, "Cooldown time between tests (seconds)")
-	verbose := flag.Bool("verbose", false, "Enable verbose logging")
-	splitRegions := flag.Bool("split-regions", true, "Whether to split the table into regions")
-	useFutureTS := flag.Bool("use-future-ts", true, "Whether to use future timestamp in queries")
-	runAllTests := flag.Bool("run-all-tests", true, "Run tests for all combinations of split-regions and use-future-ts")
-	prometheusAddr := flag.String("prometheus-addr", "127.0.0.1", "Prometheus server address")
-	prometheusPort := flag.Int("prometheus-port", 9090, "Prometheus server port")
-	collectMetrics := flag.Bool("collect-metrics", true, "Whether to collect Prometheus metrics")
-	showHelp := flag.Bool("help-prometheus", false, "Show help about Prometheus metrics collection")
-
-	flag.Parse()
-
-	// Display Prometheus help if requested
-	if *showHelp {
-		fmt.Println("\nPrometheus Metrics Collection Help:")
-		fmt.Println("====================================")
-		fmt.Println("This tool collects TSO handling metrics from Prometheus to analyze")
-		fmt.Println("how different test configurations affect PD's timestamp oracle (TSO).")
-		fmt.Println("\nTo enable metrics collection, use the following flags:")
-		fmt.Println("  -collect-metrics      : Enable metrics collection (default: true)")
-		fmt.Println("  -prometheus-addr      : Prometheus server address (required)")
-		fmt.Println("  -prometheus-port      : Prometheus server port (default: 9090)")
-		fmt.Println("\nExample:")
-		fmt.Println("  ./tidb_future_ts -collect-metrics -prometheus-addr=\"10.0.0.1\"")
-		fmt.Println("\nMetrics collected:")
-		fmt.Println("  sum(pd_client_request_handle_requests_duration_seconds_count{type=\"tso\"})")
-		fmt.Println("\nThis tool uses the counter difference method to calculate TSO requests.")
-		fmt.Println("It queries the raw counter value before and after each test run,")
-		fmt.Println("then calculates the difference to determine the exact number of TSO")
-		fmt.Println("requests that occurred during the test period.")
-		fmt.Println("====================================")
-		os.Exit(0)
+// pushResultToGateway pushes one completed TestResult's headline metrics to
+// a Prometheus Pushgateway, so results land on the same Grafana that
+// produced the TSO metrics.
+func pushResultToGateway(gatewayURL string, result TestResult) error {
+	job := "tidb_future_ts_benchmark"
+	instance := fmt.Sprintf("concurrency_%d_%s", result.Concurrency, result.Workload)
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "tidb_bench_qps %f\n", result.SuccessfulQPS)
+	fmt.Fprintf(&body, "tidb_bench_error_rate %f\n", result.ErrorRate)
+	fmt.Fprintf(&body, "tidb_bench_tso_requests %f\n", result.TSORequests)
+	fmt.Fprintf(&body, "tidb_bench_latency_ms{quantile=\"0.5\"} %f\n", result.LatencyP50Ms)
+	fmt.Fprintf(&body, "tidb_bench_latency_ms{quantile=\"0.95\"} %f\n", result.LatencyP95Ms)
+	fmt.Fprintf(&body, "tidb_bench_latency_ms{quantile=\"0.99\"} %f\n", result.LatencyP99Ms)
+	fmt.Fprintf(&body, "tidb_bench_tso_latency_ms{quantile=\"0.5\"} %f\n", result.TSOLatencyP50Ms)
+	fmt.Fprintf(&body, "tidb_bench_tso_latency_ms{quantile=\"0.95\"} %f\n", result.TSOLatencyP95Ms)
+	fmt.Fprintf(&body, "tidb_bench_tso_latency_ms{quantile=\"0.99\"} %f\n", result.TSOLatencyP99Ms)
+
+	pushURL := fmt.Sprintf("%s/metrics/job/%s/instance/%s", strings.TrimRight(gatewayURL, "/"), job, instance)
+	req, err := http.NewRequest(http.MethodPut, pushURL, strings.NewReader(body.String()))
+	if err != nil {
+		return err
 	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
 
-	// Parse concurrency levels
-	concurrencyLevels, err := parseConcurrencyLevels(*concurrencyStr)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		log.Fatalf("Invalid concurrency levels: %v", err)
+		return err
 	}
+	defer resp.Body.Close()
 
-	// Check Prometheus parameters
-	if *collectMetrics {
-		if *prometheusAddr == "" {
-			log.Fatalf("Prometheus address (-prometheus-addr) is required when -collect-metrics is enabled")
-		}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("pushgateway returned status %d", resp.StatusCode)
 	}
+	return nil
+}
 
-	// Setup signal handler
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+// remoteWriteSample is one labeled sample derived from a TestResult, ready
+// to be turned into a prompb.TimeSeries alongside the shared {split,
+// future_ts, concurrency, run_id} labels.
+type remoteWriteSample struct {
+	metric      string
+	extraLabels [][2]string // e.g. {"quantile", "0.5"}
+	value       float64
+}
+
+// resultRemoteWriteSamples builds the full sample set for one TestResult:
+// QPS, error rate, TSO request count, and the latency / TSO-latency
+// quantiles, mirroring what pushResultToGateway exposes to Pushgateway.
+func resultRemoteWriteSamples(result TestResult) []remoteWriteSample {
+	quantileLabel := func(q string) [][2]string { return [][2]string{{"quantile", q}} }
+	return []remoteWriteSample{
+		{metric: "tidb_bench_qps", value: result.SuccessfulQPS},
+		{metric: "tidb_bench_error_rate", value: result.ErrorRate},
+		{metric: "tidb_bench_tso_requests", value: result.TSORequests},
+		{metric: "tidb_bench_latency_ms", extraLabels: quantileLabel("0.5"), value: result.LatencyP50Ms},
+		{metric: "tidb_bench_latency_ms", extraLabels: quantileLabel("0.95"), value: result.LatencyP95Ms},
+		{metric: "tidb_bench_latency_ms", extraLabels: quantileLabel("0.99"), value: result.LatencyP99Ms},
+		{metric: "tidb_bench_tso_latency_ms", extraLabels: quantileLabel("0.5"), value: result.TSOLatencyP50Ms},
+		{metric: "tidb_bench_tso_latency_ms", extraLabels: quantileLabel("0.95"), value: result.TSOLatencyP95Ms},
+		{metric: "tidb_bench_tso_latency_ms", extraLabels: quantileLabel("0.99"), value: result.TSOLatencyP99Ms},
+	}
+}
+
+// buildRemoteWriteTimeSeries converts one TestResult into the prompb series
+// remote_write actually transmits, carrying {split, future_ts, concurrency,
+// run_id} on every sample so they line up with dashboard panels in Grafana.
+func buildRemoteWriteTimeSeries(result TestResult, config TestConfig, runID string) []prompb.TimeSeries {
+	tsMillis := time.Now().UnixMilli()
+	baseLabels := [][2]string{
+		{"split", strconv.FormatBool(config.SplitRegions)},
+		{"future_ts", strconv.FormatBool(config.UseFutureTS)},
+		{"concurrency", strconv.Itoa(result.Concurrency)},
+		{"run_id", runID},
+	}
+
+	var series []prompb.TimeSeries
+	for _, sample := range resultRemoteWriteSamples(result) {
+		labels := []prompb.Label{{Name: "__name__", Value: sample.metric}}
+		for _, kv := range baseLabels {
+			labels = append(labels, prompb.Label{Name: kv[0], Value: kv[1]})
+		}
+		for _, kv := range sample.extraLabels {
+			labels = append(labels, prompb.Label{Name: kv[0], Value: kv[1]})
+		}
+		series = append(series, prompb.TimeSeries{
+			Labels:  labels,
+			Samples: []prompb.Sample{{Value: sample.value, Timestamp: tsMillis}},
+		})
+	}
+	return series
+}
+
+// marshalRemoteWriteV1 encodes series as a v1 prompb.WriteRequest.
+func marshalRemoteWriteV1(series []prompb.TimeSeries) ([]byte, error) {
+	return (&prompb.WriteRequest{Timeseries: series}).Marshal()
+}
+
+// marshalRemoteWriteV2 encodes series as an io.prometheus.write.v2.Request,
+// which replaces v1's repeated string labels with a shared symbol table plus
+// per-series (name_ref, value_ref) pairs to cut payload size.
+func marshalRemoteWriteV2(series []prompb.TimeSeries) ([]byte, error) {
+	symbols := []string{""} // index 0 is reserved for the empty string
+	symbolIndex := map[string]uint32{"": 0}
+	intern := func(s string) uint32 {
+		if idx, ok := symbolIndex[s]; ok {
+			return idx
+		}
+		idx := uint32(len(symbols))
+		symbols = append(symbols, s)
+		symbolIndex[s] = idx
+		return idx
+	}
+
+	req := &writev2.Request{}
+	for _, ts := range series {
+		refs := make([]uint32, 0, len(ts.Labels)*2)
+		for _, label := range ts.Labels {
+			refs = append(refs, intern(label.Name), intern(label.Value))
+		}
+		samples := make([]writev2.Sample, 0, len(ts.Samples))
+		for _, s := range ts.Samples {
+			samples = append(samples, writev2.Sample{Value: s.Value, Timestamp: s.Timestamp})
+		}
+		req.Timeseries = append(req.Timeseries, writev2.TimeSeries{
+			LabelsRefs: refs,
+			Samples:    samples,
+		})
+	}
+	req.Symbols = symbols
+
+	return req.Marshal()
+}
+
+// pushResultRemoteWrite batches every sample from result into a single
+// remote_write request and POSTs it to remoteWriteURL. 5xx responses are
+// retried with exponential backoff; 4xx responses are treated as a
+// permanently malformed request and not retried.
+func pushResultRemoteWrite(remoteWriteURL string, v2 bool, result TestResult, config TestConfig, runID string) error {
+	series := buildRemoteWriteTimeSeries(result, config, runID)
+
+	var body []byte
+	var err error
+	protocolVersion := "0.1.0"
+	if v2 {
+		body, err = marshalRemoteWriteV2(series)
+		protocolVersion = "2.0.0"
+	} else {
+		body, err = marshalRemoteWriteV1(series)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode remote_write request: %v", err)
+	}
+	compressed := snappy.Encode(nil, body)
+
+	const maxAttempts = 5
+	backoff := 500 * time.Millisecond
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		req, err := http.NewRequest(http.MethodPost, remoteWriteURL, bytes.NewReader(compressed))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Encoding", "snappy")
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		req.Header.Set("X-Prometheus-Remote-Write-Version", protocolVersion)
+
+		resp, doErr := http.DefaultClient.Do(req)
+		if doErr != nil {
+			if attempt == maxAttempts {
+				return fmt.Errorf("remote_write request failed after %d attempts: %v", attempt, doErr)
+			}
+			time.Sleep(backoff)
+			backoff *= 2
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode < 300 {
+			return nil
+		}
+		if resp.StatusCode < 500 {
+			return fmt.Errorf("remote_write rejected with status %d (not retrying)", resp.StatusCode)
+		}
+		if attempt == maxAttempts {
+			return fmt.Errorf("remote_write failed after %d attempts, last status %d", attempt, resp.StatusCode)
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	return nil
+}
+
+// checkpointFingerprint captures the subset of TestConfig that determines
+// whether a (SetupTable, RunTest) pair can be skipped on resume. Fields like
+// Password, ConcurrencyLevels or OutputFormats don't change what was already
+// run against the table, so they're left out of the hash.
+type checkpointFingerprint struct {
+	Host, Database, TableName string
+	Port                      int
+	Vars                      map[string]string
+	Rows, RegionCount, FutureTS int
+	SplitRegions, UseFutureTS bool
+	Workload                  string
+	WorkloadRows              int
+	WorkloadStaleMS           int
+	Mode                      string
+	TargetQPS                 float64
+	ArrivalDist               string
+	MaxInflight               int
+}
+
+// configCheckpointKey returns a stable identifier for the test configuration
+// a checkpoint entry belongs to, borrowed from the same "hash the config"
+// approach TiDB Lightning uses to key its own checkpoints.
+func configCheckpointKey(config TestConfig) string {
+	fp := checkpointFingerprint{
+		Host:            config.Host,
+		Port:            config.Port,
+		Vars:            config.Vars,
+		Database:        config.Database,
+		TableName:       config.TableName,
+		Rows:            config.Rows,
+		RegionCount:     config.RegionCount,
+		FutureTS:        config.FutureTS,
+		SplitRegions:    config.SplitRegions,
+		UseFutureTS:     config.UseFutureTS,
+		Workload:        config.Workload,
+		WorkloadRows:    config.WorkloadRows,
+		WorkloadStaleMS: config.WorkloadStaleMS,
+		Mode:            config.Mode,
+		TargetQPS:       config.TargetQPS,
+		ArrivalDist:     config.ArrivalDist,
+		MaxInflight:     config.MaxInflight,
+	}
+	data, _ := json.Marshal(fp)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckpointStore persists sweep progress so a crash or restart can skip
+// (config, concurrency) pairs - and the multi-minute SetupTable step - that
+// have already completed, rather than losing the whole matrix.
+type CheckpointStore interface {
+	IsSetupDone(configKey string) (bool, error)
+	MarkSetupDone(configKey string) error
+	LoadResult(configKey string, concurrency int) (TestResult, bool, error)
+	SaveResult(configKey string, concurrency int, result TestResult) error
+}
+
+// newCheckpointStore builds a CheckpointStore for the given driver. An empty
+// path disables checkpointing entirely, so callers can pass a nil store.
+func newCheckpointStore(driver, path string, db *sql.DB) (CheckpointStore, error) {
+	if path == "" {
+		return nil, nil
+	}
+	switch driver {
+	case "", "file":
+		return newFileCheckpointStore(path)
+	case "mysql":
+		return newMySQLCheckpointStore(db, path)
+	default:
+		return nil, fmt.Errorf("unknown checkpoint driver %q (want file or mysql)", driver)
+	}
+}
+
+// fileCheckpointRecord is the on-disk shape for a single config's progress.
+type fileCheckpointRecord struct {
+	SetupDone bool                `json:"setup_done"`
+	Results   map[int]TestResult  `json:"results"`
+}
+
+// fileCheckpointStore persists checkpoint state as local JSON, rewriting the
+// whole file after each mutation so a crash loses at most the in-flight step.
+type fileCheckpointStore struct {
+	path string
+	mu   sync.Mutex
+	data map[string]*fileCheckpointRecord
+}
+
+func newFileCheckpointStore(path string) (*fileCheckpointStore, error) {
+	s := &fileCheckpointStore{path: path, data: make(map[string]*fileCheckpointRecord)}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to read checkpoint file %s: %v", path, err)
+	}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &s.data); err != nil {
+			return nil, fmt.Errorf("failed to parse checkpoint file %s: %v", path, err)
+		}
+	}
+	return s, nil
+}
+
+func (s *fileCheckpointStore) record(configKey string) *fileCheckpointRecord {
+	rec, ok := s.data[configKey]
+	if !ok {
+		rec = &fileCheckpointRecord{Results: make(map[int]TestResult)}
+		s.data[configKey] = rec
+	}
+	return rec
+}
+
+func (s *fileCheckpointStore) persistLocked() error {
+	raw, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, raw, 0644)
+}
+
+func (s *fileCheckpointStore) IsSetupDone(configKey string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.record(configKey).SetupDone, nil
+}
+
+func (s *fileCheckpointStore) MarkSetupDone(configKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record(configKey).SetupDone = true
+	return s.persistLocked()
+}
+
+func (s *fileCheckpointStore) LoadResult(configKey string, concurrency int) (TestResult, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result, ok := s.record(configKey).Results[concurrency]
+	return result, ok, nil
+}
+
+func (s *fileCheckpointStore) SaveResult(configKey string, concurrency int, result TestResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record(configKey).Results[concurrency] = result
+	return s.persistLocked()
+}
+
+// setupCheckpointConcurrency is a sentinel row in the mysql checkpoint table
+// used to record that SetupTable completed, since the table's primary key is
+// (config_key, concurrency) and -1 is never a real concurrency level.
+const setupCheckpointConcurrency = -1
+
+// mysqlCheckpointStore persists checkpoint state in the target TiDB itself,
+// so the state survives even when the client machine doesn't.
+type mysqlCheckpointStore struct {
+	db        *sql.DB
+	tableName string
+}
+
+func newMySQLCheckpointStore(db *sql.DB, tableName string) (*mysqlCheckpointStore, error) {
+	_, err := db.Exec(fmt.Sprintf(`
+        CREATE TABLE IF NOT EXISTS %s (
+            config_key VARCHAR(64) NOT NULL,
+            concurrency INT NOT NULL,
+            setup_done TINYINT NOT NULL DEFAULT 0,
+            result_json LONGTEXT,
+            PRIMARY KEY (config_key, concurrency)
+        )
+    `, tableName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint table: %v", err)
+	}
+	return &mysqlCheckpointStore{db: db, tableName: tableName}, nil
+}
+
+func (s *mysqlCheckpointStore) IsSetupDone(configKey string) (bool, error) {
+	var done int
+	query := fmt.Sprintf("SELECT setup_done FROM %s WHERE config_key = ? AND concurrency = ?", s.tableName)
+	err := s.db.QueryRow(query, configKey, setupCheckpointConcurrency).Scan(&done)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return done == 1, nil
+}
+
+func (s *mysqlCheckpointStore) MarkSetupDone(configKey string) error {
+	query := fmt.Sprintf(
+		"INSERT INTO %s (config_key, concurrency, setup_done) VALUES (?, ?, 1) ON DUPLICATE KEY UPDATE setup_done = 1",
+		s.tableName)
+	_, err := s.db.Exec(query, configKey, setupCheckpointConcurrency)
+	return err
+}
+
+func (s *mysqlCheckpointStore) LoadResult(configKey string, concurrency int) (TestResult, bool, error) {
+	var raw string
+	query := fmt.Sprintf("SELECT result_json FROM %s WHERE config_key = ? AND concurrency = ?", s.tableName)
+	err := s.db.QueryRow(query, configKey, concurrency).Scan(&raw)
+	if err == sql.ErrNoRows || raw == "" {
+		return TestResult{}, false, nil
+	}
+	if err != nil {
+		return TestResult{}, false, err
+	}
+	var result TestResult
+	if err := json.Unmarshal([]byte(raw), &result); err != nil {
+		return TestResult{}, false, err
+	}
+	return result, true, nil
+}
+
+func (s *mysqlCheckpointStore) SaveResult(configKey string, concurrency int, result TestResult) error {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf(
+		"INSERT INTO %s (config_key, concurrency, result_json) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE result_json = VALUES(result_json)",
+		s.tableName)
+	_, err = s.db.Exec(query, configKey, concurrency, string(raw))
+	return err
+}
+
+// metricsClient wraps the official Prometheus HTTP API client so callers get
+// typed model.Value results and warning propagation instead of hand-rolled
+// JSON parsing against /api/v1/query and /api/v1/query_range.
+type metricsClient struct {
+	api promv1.API
+}
+
+// newMetricsClient builds a client against the Prometheus server at addr:port.
+func newMetricsClient(addr string, port int) (*metricsClient, error) {
+	client, err := promapi.NewClient(promapi.Config{
+		Address: fmt.Sprintf("http://%s:%d", addr, port),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build Prometheus client: %v", err)
+	}
+	return &metricsClient{api: promv1.NewAPI(client)}, nil
+}
+
+// QueryInstant evaluates expr at ts and returns the raw typed result.
+func (c *metricsClient) QueryInstant(ctx context.Context, expr string, ts time.Time) (model.Value, error) {
+	value, warnings, err := c.api.Query(ctx, expr, ts)
+	if err != nil {
+		return nil, fmt.Errorf("prometheus query failed: %v", err)
+	}
+	for _, w := range warnings {
+		fmt.Printf("WARNING: Prometheus query warning: %s\n", w)
+	}
+	return value, nil
+}
+
+// QueryRange evaluates expr at each step between start and end.
+func (c *metricsClient) QueryRange(ctx context.Context, expr string, start, end time.Time, step time.Duration) (model.Value, error) {
+	value, warnings, err := c.api.QueryRange(ctx, expr, promv1.Range{Start: start, End: end, Step: step})
+	if err != nil {
+		return nil, fmt.Errorf("prometheus range query failed: %v", err)
+	}
+	for _, w := range warnings {
+		fmt.Printf("WARNING: Prometheus range query warning: %s\n", w)
+	}
+	return value, nil
+}
+
+// QuantileOverRange reads `quantile` off the PD TSO handling latency
+// histogram, rate()'d over window and evaluated at ts. Returns an error
+// (rather than a zero value) when the histogram metric has no data, so
+// callers can fall back gracefully instead of reporting a bogus zero.
+func (c *metricsClient) QuantileOverRange(ctx context.Context, quantile float64, window time.Duration, ts time.Time) (time.Duration, error) {
+	expr := fmt.Sprintf(
+		`histogram_quantile(%g, sum(rate(pd_client_request_handle_requests_duration_seconds_bucket{type="tso"}[%s])) by (le))`,
+		quantile, model.Duration(window))
+	value, err := c.QueryInstant(ctx, expr, ts)
+	if err != nil {
+		return 0, err
+	}
+	vec, ok := value.(model.Vector)
+	if !ok || len(vec) == 0 {
+		return 0, fmt.Errorf("no TSO histogram data for quantile %.2f (metric may be absent on this cluster)", quantile)
+	}
+	seconds := float64(vec[0].Value)
+	if math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return 0, fmt.Errorf("quantile %.2f resolved to a non-finite value", quantile)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// prometheusClient lazily builds and caches t's Prometheus API client.
+func (t *TestRunner) prometheusClient() (*metricsClient, error) {
+	if t.PromClient == nil {
+		client, err := newMetricsClient(t.Config.PrometheusAddr, t.Config.PrometheusPort)
+		if err != nil {
+			return nil, err
+		}
+		t.PromClient = client
+	}
+	return t.PromClient, nil
+}
+
+// queryPrometheusCounter queries the Prometheus server and returns the raw counter value
+func (t *TestRunner) queryPrometheusCounter() (float64, error) {
+	if !t.Config.CollectMetrics {
+		return 0, nil
+	}
+
+	// Check if Prometheus address is provided
+	if t.Config.PrometheusAddr == "" {
+		return 0, fmt.Errorf("Prometheus address not provided")
+	}
+
+	client, err := t.prometheusClient()
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := client.QueryInstant(t.Ctx, `sum(pd_client_request_handle_requests_duration_seconds_count{type="tso"})`, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	vec, ok := value.(model.Vector)
+	if !ok || len(vec) == 0 {
+		return 0, fmt.Errorf("no results returned for counter query")
+	}
+
+	return float64(vec[0].Value), nil
+}
+
+// tsoLatencyQuantiles samples p50/p95/p99 of the PD TSO handling latency
+// histogram over [window ending at ts], returning zero values (not an error)
+// if the histogram isn't available so RunTest can still report the rest of
+// the result.
+func (t *TestRunner) tsoLatencyQuantiles(window time.Duration, ts time.Time) (p50, p95, p99 time.Duration) {
+	client, err := t.prometheusClient()
+	if err != nil {
+		fmt.Printf("WARNING: Failed to build Prometheus API client: %v\n", err)
+		return 0, 0, 0
+	}
+	quantiles := []struct {
+		q   float64
+		out *time.Duration
+	}{
+		{0.50, &p50},
+		{0.95, &p95},
+		{0.99, &p99},
+	}
+	for _, qq := range quantiles {
+		d, err := client.QuantileOverRange(t.Ctx, qq.q, window, ts)
+		if err != nil {
+			fmt.Printf("WARNING: TSO latency p%.0f unavailable: %v\n", qq.q*100, err)
+			continue
+		}
+		*qq.out = d
+	}
+	return p50, p95, p99
+}
+
+// MetricSeriesConfig names one PromQL expression to sample over the test
+// window. Loaded from a JSON file via -metrics-config, so adding a new
+// series doesn't require a rebuild.
+type MetricSeriesConfig struct {
+	Name string `json:"name"`
+	Expr string `json:"expr"`
+}
+
+// defaultMetricSeries covers PD TSO RPC rate/latency, TiDB query latency
+// (computed from the histogram buckets), KV request counts by type, region
+// cache miss rate, and per-instance CPU/memory — the signals that matter
+// when comparing split/future-TS/workload configurations.
+var defaultMetricSeries = []MetricSeriesConfig{
+	{Name: "pd_tso_rpc_rate", Expr: `sum(rate(pd_client_request_handle_requests_duration_seconds_count{type="tso"}[1m]))`},
+	{Name: "pd_tso_rpc_p99_seconds", Expr: `histogram_quantile(0.99, sum(rate(pd_client_request_handle_requests_duration_seconds_bucket{type="tso"}[1m])) by (le))`},
+	{Name: "tidb_query_duration_p50_seconds", Expr: `histogram_quantile(0.50, sum(rate(tidb_server_handle_query_duration_seconds_bucket[1m])) by (le))`},
+	{Name: "tidb_query_duration_p99_seconds", Expr: `histogram_quantile(0.99, sum(rate(tidb_server_handle_query_duration_seconds_bucket[1m])) by (le))`},
+	{Name: "tikv_kv_request_rate", Expr: `sum(rate(tikv_grpc_msg_duration_seconds_count[1m])) by (type)`},
+	{Name: "tidb_region_cache_miss_rate", Expr: `sum(rate(tidb_tikvclient_region_cache_miss_total[1m]))`},
+	{Name: "instance_cpu_seconds_rate", Expr: `sum(rate(process_cpu_seconds_total{job=~"tikv|pd"}[1m])) by (instance)`},
+	{Name: "instance_memory_bytes", Expr: `sum(process_resident_memory_bytes{job=~"tikv|pd"}) by (instance)`},
+}
+
+// loadMetricSeriesConfig reads a JSON array of MetricSeriesConfig from path.
+func loadMetricSeriesConfig(path string) ([]MetricSeriesConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metrics config: %v", err)
+	}
+	var series []MetricSeriesConfig
+	if err := json.Unmarshal(data, &series); err != nil {
+		return nil, fmt.Errorf("failed to parse metrics config: %v", err)
+	}
+	return series, nil
+}
+
+// MetricSample is one (timestamp, value) point from a Prometheus range query.
+type MetricSample struct {
+	Timestamp float64 `json:"timestamp"`
+	Value     float64 `json:"value"`
+}
+
+// MetricSeriesResult is the full time series sampled for one expression over
+// the test window, plus a few summary statistics for the text report.
+type MetricSeriesResult struct {
+	Name    string         `json:"name"`
+	Expr    string         `json:"expr"`
+	Samples []MetricSample `json:"samples,omitempty"`
+	Min     float64        `json:"min"`
+	Avg     float64        `json:"avg"`
+	Max     float64        `json:"max"`
+	P99     float64        `json:"p99"`
+}
+
+// MetricsCollector issues query_range requests against Prometheus for a
+// configured set of expressions over an exact test window.
+type MetricsCollector struct {
+	Addr   string
+	Port   int
+	Series []MetricSeriesConfig
+	client *metricsClient
+}
+
+// NewMetricsCollector builds a collector with the default series plus any
+// series loaded from configPath (if non-empty).
+func NewMetricsCollector(addr string, port int, configPath string) (*MetricsCollector, error) {
+	series := defaultMetricSeries
+	if configPath != "" {
+		extra, err := loadMetricSeriesConfig(configPath)
+		if err != nil {
+			return nil, err
+		}
+		series = append(append([]MetricSeriesConfig{}, series...), extra...)
+	}
+	return &MetricsCollector{Addr: addr, Port: port, Series: series}, nil
+}
+
+// CollectRange samples every configured series over [start, end] at the
+// given step, returning one MetricSeriesResult per series. A series that
+// Prometheus has no data for (e.g. the metric doesn't exist on this
+// cluster) is skipped with a warning rather than failing the whole test.
+func (m *MetricsCollector) CollectRange(ctx context.Context, start, end time.Time, step time.Duration) []MetricSeriesResult {
+	var results []MetricSeriesResult
+	for _, series := range m.Series {
+		seriesResults, err := m.queryRange(ctx, series, start, end, step)
+		if err != nil {
+			fmt.Printf("WARNING: metrics series %q: %v\n", series.Name, err)
+			continue
+		}
+		results = append(results, seriesResults...)
+	}
+	return results
+}
+
+// seriesLabelSuffix renders a matrix sample's label set (minus __name__) as
+// a "{k="v", ...}" suffix, so a grouped expression like "by (instance)"
+// yields one distinguishable name per instance/type instead of collapsing
+// them all into a single unlabeled result.
+func seriesLabelSuffix(metric model.Metric) string {
+	names := make([]string, 0, len(metric))
+	for name := range metric {
+		if name == model.MetricNameLabel {
+			continue
+		}
+		names = append(names, string(name))
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", name, metric[model.LabelName(name)]))
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// queryRange samples series.Expr over [start, end] and returns one
+// MetricSeriesResult per label set in the returned matrix, so expressions
+// grouped "by (instance)" or "by (type)" report every instance/type rather
+// than only the first one Prometheus happens to return.
+func (m *MetricsCollector) queryRange(ctx context.Context, series MetricSeriesConfig, start, end time.Time, step time.Duration) ([]MetricSeriesResult, error) {
+	if m.client == nil {
+		client, err := newMetricsClient(m.Addr, m.Port)
+		if err != nil {
+			return nil, err
+		}
+		m.client = client
+	}
+
+	value, err := m.client.QueryRange(ctx, series.Expr, start, end, step)
+	if err != nil {
+		return nil, err
+	}
+
+	matrix, ok := value.(model.Matrix)
+	if !ok || len(matrix) == 0 {
+		return nil, fmt.Errorf("no results returned")
+	}
+
+	results := make([]MetricSeriesResult, 0, len(matrix))
+	for _, sampleStream := range matrix {
+		name := series.Name + seriesLabelSuffix(sampleStream.Metric)
+		result := MetricSeriesResult{Name: name, Expr: series.Expr}
+		var sum float64
+		var values []float64
+		for _, sample := range sampleStream.Values {
+			value := float64(sample.Value)
+			result.Samples = append(result.Samples, MetricSample{
+				Timestamp: float64(sample.Timestamp.Unix()),
+				Value:     value,
+			})
+			values = append(values, value)
+			sum += value
+		}
+
+		if len(values) == 0 {
+			continue
+		}
+
+		sort.Float64s(values)
+		result.Min = values[0]
+		result.Max = values[len(values)-1]
+		result.Avg = sum / float64(len(values))
+		p99Idx := int(math.Ceil(0.99*float64(len(values)))) - 1
+		if p99Idx < 0 {
+			p99Idx = 0
+		}
+		if p99Idx >= len(values) {
+			p99Idx = len(values) - 1
+		}
+		result.P99 = values[p99Idx]
+
+		results = append(results, result)
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no numeric samples returned")
+	}
+
+	return results, nil
+}
+
+// commonFlags holds the connection, workload, metrics and output flags
+// shared by the "run" and "sweep" subcommands, so each one only needs to
+// declare the flags that actually change its behavior.
+type commonFlags struct {
+	host             *string
+	port             *int
+	user             *string
+	password         *string
+	database         *string
+	tlsCA            *string
+	tlsCert          *string
+	tlsKey           *string
+	maxAllowedPacket *int
+	dialTimeout      *time.Duration
+	readTimeout      *time.Duration
+	writeTimeout     *time.Duration
+	connMaxLifetime  *time.Duration
+	maxOpenConns     *int
+	maxIdleConns     *int
+	sessionVars      *string
+
+	tableName      *string
+	rows           *int
+	regionCount    *int
+	duration       *int
+	concurrencyStr *string
+	futureTS       *int
+	cooldown       *int
+	verbose        *bool
+
+	prometheusAddr *string
+	prometheusPort *int
+	collectMetrics *bool
+
+	workload        *string
+	workloadRows    *int
+	workloadStaleMS *int
+
+	mode        *string
+	targetQPS   *float64
+	arrivalDist *string
+	maxInflight *int
+
+	metricsConfigPath *string
+	metricsStep       *int
+
+	outputFormat   *string
+	pushgatewayURL *string
+
+	checkpointPath   *string
+	checkpointDriver *string
+	restart          *bool
+
+	remoteWriteURL *string
+	remoteWriteV2  *bool
+}
+
+// registerCommonFlags registers the flags shared by "run" and "sweep" on fs
+// and returns pointers to their values.
+func registerCommonFlags(fs *flag.FlagSet) *commonFlags {
+	c := &commonFlags{}
+	c.host = fs.String("host", "127.0.0.1", "TiDB host")
+	c.port = fs.Int("port", 4000, "TiDB port")
+	c.user = fs.String("user", "root", "TiDB username")
+	c.password = fs.String("password", "", "TiDB password")
+	c.database = fs.String("database", "test", "Database name")
+	c.tlsCA = fs.String("tls-ca", "", "Path to the TLS CA certificate; requires -tls-cert and -tls-key")
+	c.tlsCert = fs.String("tls-cert", "", "Path to the TLS client certificate; requires -tls-ca and -tls-key")
+	c.tlsKey = fs.String("tls-key", "", "Path to the TLS client key; requires -tls-ca and -tls-cert")
+	c.maxAllowedPacket = fs.Int("max-allowed-packet", 0, "Max allowed packet size in bytes (0: driver default)")
+	c.dialTimeout = fs.Duration("dial-timeout", 10*time.Second, "Connection dial timeout")
+	c.readTimeout = fs.Duration("read-timeout", 0, "Connection read timeout (0: driver default)")
+	c.writeTimeout = fs.Duration("write-timeout", 0, "Connection write timeout (0: driver default)")
+	c.connMaxLifetime = fs.Duration("conn-max-lifetime", time.Hour, "Max lifetime of a pooled connection")
+	c.maxOpenConns = fs.Int("max-open-conns", 200, "Max open connections in the pool")
+	c.maxIdleConns = fs.Int("max-idle-conns", 100, "Max idle connections in the pool")
+	c.sessionVars = fs.String("session-vars", "", "Comma-separated session variables to SET after connecting, e.g. tidb_enable_async_commit=1,tidb_replica_read=leader")
+	c.tableName = fs.String("table-name", "future_ts_test", "Test table name")
+	c.rows = fs.Int("rows", 1000000, "Number of rows in test table")
+	c.regionCount = fs.Int("region-count", 1000, "Number of regions to split table into")
+	c.duration = fs.Int("duration", 60, "Duration of each test (seconds)")
+	c.concurrencyStr = fs.String("concurrency", "16", "Comma-separated list of concurrency levels to test")
+	c.futureTS = fs.Int("future-ts", 1000, "Fixed future timestamp in milliseconds")
+	c.cooldown = fs.Int("cooldown", 60, "Cooldown time between tests (seconds)")
+	c.verbose = fs.Bool("verbose", false, "Enable verbose logging")
+	c.prometheusAddr = fs.String("prometheus-addr", "127.0.0.1", "Prometheus server address")
+	c.prometheusPort = fs.Int("prometheus-port", 9090, "Prometheus server port")
+	c.collectMetrics = fs.Bool("collect-metrics", true, "Whether to collect Prometheus metrics")
+	c.workload = fs.String("workload", "count", "Workload to run: count, point-get, range-scan, stale-read, follower-read, mixed")
+	c.workloadRows = fs.Int("workload-rows", 100, "Rows fetched per iteration by the range-scan workload")
+	c.workloadStaleMS = fs.Int("workload-stale-ms", 1000, "Staleness offset in milliseconds for the stale-read workload")
+	c.mode = fs.String("mode", "closed", "Load generator mode: closed (goroutine-per-client) or open (fixed arrival rate)")
+	c.targetQPS = fs.Float64("target-qps", 100, "Target arrival rate for -mode=open")
+	c.arrivalDist = fs.String("arrival-dist", "poisson", "Arrival process for -mode=open: poisson or uniform")
+	c.maxInflight = fs.Int("max-inflight", 0, "Cap on in-flight requests for -mode=open (0: use the concurrency level)")
+	c.metricsConfigPath = fs.String("metrics-config", "", "Optional JSON file of extra {name,expr} PromQL series to sample alongside the defaults")
+	c.metricsStep = fs.Int("metrics-step", 15, "Step in seconds for Prometheus query_range sampling")
+	c.outputFormat = fs.String("output-format", "text,json", "Comma-separated result formats: text, json, jsonl, csv, pushgateway, markdown")
+	c.pushgatewayURL = fs.String("pushgateway-url", "", "Prometheus Pushgateway base URL, required when -output-format includes pushgateway")
+	c.checkpointPath = fs.String("checkpoint", "", "Enable checkpoint/resume; path to the checkpoint file (-checkpoint-driver=file) or checkpoint table name (-checkpoint-driver=mysql)")
+	c.checkpointDriver = fs.String("checkpoint-driver", "file", "Where checkpoint state lives: file or mysql (the target TiDB itself)")
+	c.restart = fs.Bool("restart", false, "Ignore any existing checkpoint entries and rerun everything")
+	c.remoteWriteURL = fs.String("remote-write-url", "", "Optional Prometheus remote_write endpoint to push each TestResult to")
+	c.remoteWriteV2 = fs.Bool("remote-write-v2", false, "Use the remote_write 2.0 protocol (io.prometheus.write.v2.Request) instead of 1.0")
+	return c
+}
+
+// resolve validates the common flags and parses their string-encoded values
+// (concurrency levels, output formats, session variables) into the types
+// TestConfig expects.
+func (c *commonFlags) resolve() (concurrencyLevels []int, outputFormats []string, sessionVarsMap map[string]string, err error) {
+	concurrencyLevels, err = parseConcurrencyLevels(*c.concurrencyStr)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid concurrency levels: %v", err)
+	}
+	if *c.collectMetrics && *c.prometheusAddr == "" {
+		return nil, nil, nil, fmt.Errorf("prometheus address (-prometheus-addr) is required when -collect-metrics is enabled")
+	}
+	if _, err := newWorkload(*c.workload, TestConfig{}); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid workload: %v", err)
+	}
+	outputFormats, err = parseOutputFormats(*c.outputFormat)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid output format: %v", err)
+	}
+	if hasFormat(outputFormats, "pushgateway") && *c.pushgatewayURL == "" {
+		return nil, nil, nil, fmt.Errorf("-pushgateway-url is required when -output-format includes pushgateway")
+	}
+	sessionVarsMap, err = parseSessionVars(*c.sessionVars)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid -session-vars: %v", err)
+	}
+	if *c.mode == "open" && *c.targetQPS <= 0 {
+		return nil, nil, nil, fmt.Errorf("-target-qps must be positive when -mode=open")
+	}
+	return concurrencyLevels, outputFormats, sessionVarsMap, nil
+}
+
+// buildBaseConfig assembles a TestConfig from the parsed common flags plus
+// the values resolve() parses (concurrency levels, output formats, session
+// variables). SplitRegions and UseFutureTS are left false for the caller to
+// set.
+func (c *commonFlags) buildBaseConfig(concurrencyLevels []int, outputFormats []string, sessionVarsMap map[string]string) TestConfig {
+	return TestConfig{
+		MySQLConnectParam: MySQLConnectParam{
+			Host:             *c.host,
+			Port:             *c.port,
+			User:             *c.user,
+			Password:         *c.password,
+			Database:         *c.database,
+			TLSCAPath:        *c.tlsCA,
+			TLSCertPath:      *c.tlsCert,
+			TLSKeyPath:       *c.tlsKey,
+			MaxAllowedPacket: *c.maxAllowedPacket,
+			DialTimeout:      *c.dialTimeout,
+			ReadTimeout:      *c.readTimeout,
+			WriteTimeout:     *c.writeTimeout,
+			ConnMaxLifetime:  *c.connMaxLifetime,
+			MaxOpenConns:     *c.maxOpenConns,
+			MaxIdleConns:     *c.maxIdleConns,
+			Vars:             sessionVarsMap,
+		},
+		TableName:         *c.tableName,
+		Rows:              *c.rows,
+		RegionCount:       *c.regionCount,
+		Duration:          *c.duration,
+		ConcurrencyLevels: concurrencyLevels,
+		FutureTS:          *c.futureTS,
+		Cooldown:          *c.cooldown,
+		Verbose:           *c.verbose,
+		PrometheusAddr:    *c.prometheusAddr,
+		PrometheusPort:    *c.prometheusPort,
+		CollectMetrics:    *c.collectMetrics,
+		Workload:          *c.workload,
+		WorkloadRows:      *c.workloadRows,
+		WorkloadStaleMS:   *c.workloadStaleMS,
+		Mode:              *c.mode,
+		TargetQPS:         *c.targetQPS,
+		ArrivalDist:       *c.arrivalDist,
+		MaxInflight:       *c.maxInflight,
+		MetricsConfigPath: *c.metricsConfigPath,
+		MetricsStep:       *c.metricsStep,
+		OutputFormats:     outputFormats,
+		PushgatewayURL:    *c.pushgatewayURL,
+		CheckpointPath:    *c.checkpointPath,
+		CheckpointDriver:  *c.checkpointDriver,
+		Restart:           *c.restart,
+		RemoteWriteURL:    *c.remoteWriteURL,
+		RemoteWriteV2:     *c.remoteWriteV2,
+		RunID:             fmt.Sprintf("%d", time.Now().UnixNano()),
+	}
+}
+
+// newRunContext wires a context that's cancelled on SIGINT/SIGTERM, so
+// in-flight Prometheus queries (which accept a context) stop promptly
+// instead of outliving the process.
+func newRunContext() (context.Context, func()) {
+	runCtx, cancelRun := context.WithCancel(context.Background())
 
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-sigChan
 		fmt.Println("Test interrupted")
+		cancelRun()
 		os.Exit(0)
 	}()
 
-	// Create base config
-	baseConfig := TestConfig{
-		Host:              *host,
-		Port:              *port,
-		User:              *user,
-		Password:          *password,
-		Database:          *database,
-		TableName:         *tableName,
-		Rows:              *rows,
-		RegionCount:       *regionCount,
-		Duration:          *duration,
-		ConcurrencyLevels: concurrencyLevels,
-		FutureTS:          *futureTS,
-		Cooldown:          *cooldown,
-		Verbose:           *verbose,
-		SplitRegions:      *splitRegions,
-		UseFutureTS:       *useFutureTS,
-		PrometheusAddr:    *prometheusAddr,
-		PrometheusPort:    *prometheusPort,
-		CollectMetrics:    *collectMetrics,
+	return runCtx, cancelRun
+}
+
+// runOneConfig connects, applies checkpoint state, sets up the table and
+// runs every concurrency level for a single TestConfig. It's shared by
+// cmdRun's run-all-tests loop, its single-config branch and cmdSweep's
+// matrix loop.
+func runOneConfig(ctx context.Context, config TestConfig) (*TestRunner, error) {
+	runner := NewTestRunner(config)
+	runner.Ctx = ctx
+	if err := runner.Connect(); err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
+	}
+
+	checkpoint, err := newCheckpointStore(config.CheckpointDriver, config.CheckpointPath, runner.DB)
+	if err != nil {
+		runner.Close()
+		return nil, fmt.Errorf("failed to initialize checkpoint store: %v", err)
+	}
+	runner.Checkpoint = checkpoint
+
+	if err := runner.SetupTable(); err != nil {
+		runner.Close()
+		return nil, fmt.Errorf("failed to setup table: %v", err)
 	}
 
-	// Create a test runner group to collect all runners
+	for _, concurrency := range config.ConcurrencyLevels {
+		runner.RunTest(concurrency)
+	}
+
+	return runner, nil
+}
+
+// cmdRun implements "bench run": either the legacy 2x2 (split-regions x
+// use-future-ts) sweep, or a single configuration, against one TiDB cluster.
+func cmdRun(args []string) {
+	fs := flag.NewFlagSet("bench run", flag.ExitOnError)
+	c := registerCommonFlags(fs)
+	splitRegions := fs.Bool("split-regions", true, "Whether to split the table into regions")
+	useFutureTS := fs.Bool("use-future-ts", true, "Whether to use future timestamp in queries")
+	runAllTests := fs.Bool("run-all-tests", true, "Run tests for all combinations of split-regions and use-future-ts")
+	showHelp := fs.Bool("help-prometheus", false, "Show help about Prometheus metrics collection")
+	fs.Parse(args)
+
+	if *showHelp {
+		fmt.Println("\nPrometheus Metrics Collection Help:")
+		fmt.Println("====================================")
+		fmt.Println("This tool collects TSO handling metrics from Prometheus to analyze")
+		fmt.Println("how different test configurations affect PD's timestamp oracle (TSO).")
+		fmt.Println("\nTo enable metrics collection, use the following flags:")
+		fmt.Println("  -collect-metrics      : Enable metrics collection (default: true)")
+		fmt.Println("  -prometheus-addr      : Prometheus server address (required)")
+		fmt.Println("  -prometheus-port      : Prometheus server port (default: 9090)")
+		fmt.Println("\nExample:")
+		fmt.Println("  ./tidb_future_ts_benchmark run -collect-metrics -prometheus-addr=\"10.0.0.1\"")
+		fmt.Println("\nMetrics collected:")
+		fmt.Println("  sum(pd_client_request_handle_requests_duration_seconds_count{type=\"tso\"})")
+		fmt.Println("\nThis tool uses the counter difference method to calculate TSO requests.")
+		fmt.Println("It queries the raw counter value before and after each test run,")
+		fmt.Println("then calculates the difference to determine the exact number of TSO")
+		fmt.Println("requests that occurred during the test period.")
+		fmt.Println("====================================")
+		os.Exit(0)
+	}
+
+	concurrencyLevels, outputFormats, sessionVarsMap, err := c.resolve()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	baseConfig := c.buildBaseConfig(concurrencyLevels, outputFormats, sessionVarsMap)
+
+	runCtx, cancelRun := newRunContext()
+	defer cancelRun()
+
 	runnerGroup := NewTestRunnerGroup()
-	
-	// Run tests based on configuration
+
 	if *runAllTests {
-		// Run all combinations
 		combinations := []struct {
-			split      bool
+			split       bool
 			useFutureTS bool
 		}{
 			{false, false},
@@ -1129,67 +3028,595 @@ func main() {
 			fmt.Printf("Split Regions: %v, Use Future TS: %v\n", combo.split, combo.useFutureTS)
 			fmt.Printf("========================================================\n")
 
-			runner := NewTestRunner(config)
-			if err := runner.Connect(); err != nil {
-				log.Fatalf("Failed to connect to database: %v", err)
-			}
-
-			// Always setup the table for each test combination to ensure proper region configuration
 			fmt.Printf("Setting up new table for test combination %d...\n", i+1)
-			if err := runner.SetupTable(); err != nil {
-				log.Fatalf("Failed to setup table: %v", err)
-			}
-
-			// For individual test runs, don't generate the reports to file
-			// This improves performance and reduces file clutter
-			for _, concurrency := range config.ConcurrencyLevels {
-				runner.RunTest(concurrency)
+			runner, err := runOneConfig(runCtx, config)
+			if err != nil {
+				log.Fatalf("%v", err)
 			}
-			
 			runner.Close()
-			
-			// Add runner to the group for comparison report
 			runnerGroup.AddRunner(runner)
 
-			// Extra cooldown between test combinations
-			fmt.Printf("Waiting for extra cooldown between test combinations, %d seconds...\n", *cooldown)
-			time.Sleep(time.Duration(*cooldown) * time.Second)
+			fmt.Printf("Waiting for extra cooldown between test combinations, %d seconds...\n", *c.cooldown)
+			time.Sleep(time.Duration(*c.cooldown) * time.Second)
 		}
-		
-		// Generate comparison report after all tests - this will be our single output file
+
 		fmt.Println("\nGenerating comprehensive report for all test configurations...")
 		runnerGroup.GenerateComparisonReport()
-		
+
 		fmt.Println("\nAll tests completed successfully!")
 	} else {
-		// Run a single test with the specified configuration
 		config := baseConfig
 		config.SplitRegions = *splitRegions
 		config.UseFutureTS = *useFutureTS
 
-		runner := NewTestRunner(config)
-		if err := runner.Connect(); err != nil {
-			log.Fatalf("Failed to connect to database: %v", err)
+		fmt.Println("Setting up test table...")
+		runner, err := runOneConfig(runCtx, config)
+		if err != nil {
+			log.Fatalf("%v", err)
 		}
 		defer runner.Close()
 
-		// Always setup the table before running tests
-		fmt.Println("Setting up test table...")
-		if err := runner.SetupTable(); err != nil {
-			log.Fatalf("Failed to setup table: %v", err)
+		runner.GenerateReport()
+		runnerGroup.AddRunner(runner)
+
+		fmt.Println("\nTest completed successfully!")
+	}
+}
+
+// TestPlan describes a "bench sweep" matrix: a set of independent axes whose
+// Cartesian product is the list of configurations to run. Any axis left
+// empty falls back to the corresponding -flag's value, so a plan only needs
+// to specify the axes it actually wants to vary. split_regions and
+// use_future_ts have no sweep flag and fall back to false.
+type TestPlan struct {
+	SplitRegions []bool              `yaml:"split_regions"`
+	UseFutureTS  []bool              `yaml:"use_future_ts"`
+	FutureTSMs   []int               `yaml:"future_ts_ms"`
+	Concurrency  [][]int             `yaml:"concurrency"`
+	Rows         []int               `yaml:"rows"`
+	RegionCount  []int               `yaml:"region_count"`
+	SessionVars  []map[string]string `yaml:"session_vars"`
+}
+
+// loadTestPlan reads and parses a YAML test plan file.
+func loadTestPlan(path string) (TestPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return TestPlan{}, err
+	}
+	var plan TestPlan
+	if err := yaml.Unmarshal(data, &plan); err != nil {
+		return TestPlan{}, fmt.Errorf("parsing test plan %s: %v", path, err)
+	}
+	return plan, nil
+}
+
+// expandTestPlan expands a TestPlan's axes into the Cartesian product of
+// TestConfigs to run, layered on top of base.
+func expandTestPlan(plan TestPlan, base TestConfig) []TestConfig {
+	splits := plan.SplitRegions
+	if len(splits) == 0 {
+		splits = []bool{base.SplitRegions}
+	}
+	futureTSFlags := plan.UseFutureTS
+	if len(futureTSFlags) == 0 {
+		futureTSFlags = []bool{base.UseFutureTS}
+	}
+	futureTSValues := plan.FutureTSMs
+	if len(futureTSValues) == 0 {
+		futureTSValues = []int{base.FutureTS}
+	}
+	concurrencies := plan.Concurrency
+	if len(concurrencies) == 0 {
+		concurrencies = [][]int{base.ConcurrencyLevels}
+	}
+	rowCounts := plan.Rows
+	if len(rowCounts) == 0 {
+		rowCounts = []int{base.Rows}
+	}
+	regionCounts := plan.RegionCount
+	if len(regionCounts) == 0 {
+		regionCounts = []int{base.RegionCount}
+	}
+	sessionVarSets := plan.SessionVars
+	if len(sessionVarSets) == 0 {
+		sessionVarSets = []map[string]string{base.Vars}
+	}
+
+	var configs []TestConfig
+	for _, split := range splits {
+		for _, useFutureTS := range futureTSFlags {
+			for _, futureTS := range futureTSValues {
+				for _, concurrency := range concurrencies {
+					for _, rows := range rowCounts {
+						for _, regionCount := range regionCounts {
+							for _, vars := range sessionVarSets {
+								config := base
+								config.SplitRegions = split
+								config.UseFutureTS = useFutureTS
+								config.FutureTS = futureTS
+								config.ConcurrencyLevels = concurrency
+								config.Rows = rows
+								config.RegionCount = regionCount
+								config.Vars = vars
+								configs = append(configs, config)
+							}
+						}
+					}
+				}
+			}
 		}
+	}
+	return configs
+}
+
+// cmdSweep implements "bench sweep": runs every configuration in a YAML test
+// plan's matrix against one TiDB cluster and produces one comparison report.
+func cmdSweep(args []string) {
+	fs := flag.NewFlagSet("bench sweep", flag.ExitOnError)
+	planPath := fs.String("plan", "", "Path to a YAML test plan describing the sweep matrix (required)")
+	c := registerCommonFlags(fs)
+	fs.Parse(args)
+
+	if *planPath == "" {
+		log.Fatalf("-plan is required")
+	}
+	plan, err := loadTestPlan(*planPath)
+	if err != nil {
+		log.Fatalf("Failed to load test plan: %v", err)
+	}
 
-		// For single configuration, run all tests and generate the report
-		for _, concurrency := range config.ConcurrencyLevels {
-			runner.RunTest(concurrency)
+	concurrencyLevels, outputFormats, sessionVarsMap, err := c.resolve()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	baseConfig := c.buildBaseConfig(concurrencyLevels, outputFormats, sessionVarsMap)
+
+	configs := expandTestPlan(plan, baseConfig)
+	if len(configs) == 0 {
+		log.Fatalf("Test plan %s expanded to zero configurations", *planPath)
+	}
+
+	runCtx, cancelRun := newRunContext()
+	defer cancelRun()
+
+	runnerGroup := NewTestRunnerGroup()
+	for i, config := range configs {
+		fmt.Printf("\n========================================================\n")
+		fmt.Printf("RUNNING SWEEP CONFIGURATION %d of %d\n", i+1, len(configs))
+		fmt.Printf("Split Regions: %v, Use Future TS: %v (%dms), Concurrency: %v, Rows: %d, Regions: %d\n",
+			config.SplitRegions, config.UseFutureTS, config.FutureTS, config.ConcurrencyLevels, config.Rows, config.RegionCount)
+		fmt.Printf("========================================================\n")
+
+		runner, err := runOneConfig(runCtx, config)
+		if err != nil {
+			log.Fatalf("%v", err)
 		}
-		
-		// Generate report for this single configuration
-		runner.GenerateReport()
-		
-		// Add runner to the group for the report
+		runner.Close()
 		runnerGroup.AddRunner(runner)
-		
-		fmt.Println("\nTest completed successfully!")
+
+		if i < len(configs)-1 {
+			fmt.Printf("Waiting for extra cooldown between sweep configurations, %d seconds...\n", *c.cooldown)
+			time.Sleep(time.Duration(*c.cooldown) * time.Second)
+		}
+	}
+
+	fmt.Println("\nGenerating comprehensive report for the sweep...")
+	runnerGroup.GenerateComparisonReport()
+	fmt.Println("\nSweep completed successfully!")
+}
+
+// comparisonConfigKeyPattern matches the configKey GenerateComparisonReport
+// writes into tidb_test_comparison_*.json (e.g.
+// "count_Split1000_Future1000_rows-1000000_conc-[16]") as well as the plain
+// "split_%v_futurets_%v" form, so report/analyze can parse either back into
+// the two axes distinguishing a saved configuration. The other axes in a
+// configKey are not recovered.
+var comparisonConfigKeyPattern = regexp.MustCompile(`^(?:split_(true|false)_futurets_(true|false)|[^_]*_(No Split|Split\d+)_(Regular|Future\d+)_rows-.*)$`)
+
+func parseComparisonConfigKey(key string) (splitRegions, useFutureTS bool, err error) {
+	m := comparisonConfigKeyPattern.FindStringSubmatch(key)
+	if m == nil {
+		return false, false, fmt.Errorf("unrecognized config key %q", key)
+	}
+	if m[1] != "" {
+		return m[1] == "true", m[2] == "true", nil
+	}
+	return m[3] != "No Split", m[4] != "Regular", nil
+}
+
+// loadComparisonFile loads one tidb_test_comparison_*.json file into one
+// TestRunner per config key it contains, so "report" and "analyze" can feed
+// previously-saved results straight back into TestRunnerGroup.
+func loadComparisonFile(path string) ([]*TestRunner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var resultsMap map[string]map[int]TestResult
+	if err := json.Unmarshal(data, &resultsMap); err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", path, err)
+	}
+
+	var runners []*TestRunner
+	for key, results := range resultsMap {
+		splitRegions, useFutureTS, err := parseComparisonConfigKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		config := TestConfig{SplitRegions: splitRegions, UseFutureTS: useFutureTS}
+		runners = append(runners, &TestRunner{Config: config, TestResults: results})
+	}
+	return runners, nil
+}
+
+// loadComparisonFiles loads a comma-separated list of comparison JSON files.
+func loadComparisonFiles(csvPaths string) ([]*TestRunner, error) {
+	var runners []*TestRunner
+	for _, path := range strings.Split(csvPaths, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		fileRunners, err := loadComparisonFile(path)
+		if err != nil {
+			return nil, err
+		}
+		runners = append(runners, fileRunners...)
+	}
+	return runners, nil
+}
+
+// cmdReport implements "bench report": ingests one or more previously-saved
+// tidb_test_comparison_*.json files and regenerates the comparison report,
+// reusing TestRunnerGroup.GenerateComparisonReport.
+func cmdReport(args []string) {
+	fs := flag.NewFlagSet("bench report", flag.ExitOnError)
+	input := fs.String("input", "", "Comma-separated tidb_test_comparison_*.json files to merge into one comparison report (required)")
+	outputFormat := fs.String("output-format", "text,csv", "Comma-separated report formats to (re)generate: text, json, csv, markdown")
+	fs.Parse(args)
+
+	if *input == "" {
+		log.Fatalf("-input is required")
+	}
+	outputFormats, err := parseOutputFormats(*outputFormat)
+	if err != nil {
+		log.Fatalf("Invalid -output-format: %v", err)
+	}
+
+	runners, err := loadComparisonFiles(*input)
+	if err != nil {
+		log.Fatalf("Failed to load -input: %v", err)
+	}
+	if len(runners) == 0 {
+		log.Fatalf("No configurations found in -input")
+	}
+
+	group := NewTestRunnerGroup()
+	for _, runner := range runners {
+		runner.Config.OutputFormats = outputFormats
+		group.AddRunner(runner)
+	}
+	group.GenerateComparisonReport()
+}
+
+// mean returns the arithmetic mean of values, or 0 for an empty slice.
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// collectQPSSamples gathers the per-concurrency SuccessfulQPS values from
+// every runner whose config matches (splitRegions, useFutureTS) - one sample
+// per matching runner (i.e. per input file, for repeated runs of the same
+// configuration).
+func collectQPSSamples(runners []*TestRunner, splitRegions, useFutureTS bool) map[int][]float64 {
+	samples := make(map[int][]float64)
+	for _, r := range runners {
+		if r.Config.SplitRegions != splitRegions || r.Config.UseFutureTS != useFutureTS {
+			continue
+		}
+		for level, result := range r.TestResults {
+			samples[level] = append(samples[level], result.SuccessfulQPS)
+		}
+	}
+	return samples
+}
+
+// normalCDF returns the standard normal CDF at x.
+func normalCDF(x float64) float64 {
+	return 0.5 * (1 + math.Erf(x/math.Sqrt2))
+}
+
+// lgamma is a float64-returning wrapper around math.Lgamma, which also
+// returns a sign we don't need here (the incomplete beta function only
+// ever evaluates it on positive arguments).
+func lgamma(x float64) float64 {
+	v, _ := math.Lgamma(x)
+	return v
+}
+
+// betacf is the continued-fraction part of the regularized incomplete beta
+// function (Numerical Recipes in C, 2nd ed., section 6.4), iterated to
+// convergence.
+func betacf(x, a, b float64) float64 {
+	const maxIter = 200
+	const eps = 3e-12
+	const fpmin = 1e-300
+
+	qab := a + b
+	qap := a + 1
+	qam := a - 1
+	c := 1.0
+	d := 1 - qab*x/qap
+	if math.Abs(d) < fpmin {
+		d = fpmin
+	}
+	d = 1 / d
+	h := d
+
+	for m := 1; m <= maxIter; m++ {
+		mf := float64(m)
+		m2 := 2 * mf
+
+		aa := mf * (b - mf) * x / ((qam + m2) * (a + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < fpmin {
+			d = fpmin
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < fpmin {
+			c = fpmin
+		}
+		d = 1 / d
+		h *= d * c
+
+		aa = -(a + mf) * (qab + mf) * x / ((a + m2) * (qap + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < fpmin {
+			d = fpmin
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < fpmin {
+			c = fpmin
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+
+		if math.Abs(del-1) < eps {
+			break
+		}
+	}
+	return h
+}
+
+// regularizedIncompleteBeta computes I_x(a, b).
+func regularizedIncompleteBeta(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lbeta := lgamma(a+b) - lgamma(a) - lgamma(b) + a*math.Log(x) + b*math.Log(1-x)
+	front := math.Exp(lbeta)
+	if x < (a+1)/(a+b+2) {
+		return front * betacf(x, a, b) / a
+	}
+	return 1 - front*betacf(1-x, b, a)/b
+}
+
+// studentTCDF returns P(T <= t) for a Student's t distribution with df
+// degrees of freedom, via the regularized incomplete beta function.
+func studentTCDF(t, df float64) float64 {
+	x := df / (df + t*t)
+	ib := regularizedIncompleteBeta(x, df/2, 0.5)
+	if t > 0 {
+		return 1 - 0.5*ib
+	}
+	return 0.5 * ib
+}
+
+// pairedTTest runs a two-sided paired t-test on equal-length sample slices a
+// and b, returning the t statistic, degrees of freedom and p-value.
+func pairedTTest(a, b []float64) (t, df, p float64, err error) {
+	if len(a) != len(b) || len(a) < 2 {
+		return 0, 0, 0, fmt.Errorf("need at least 2 paired samples, got %d vs %d", len(a), len(b))
+	}
+	n := len(a)
+	diffs := make([]float64, n)
+	for i := range a {
+		diffs[i] = a[i] - b[i]
+	}
+	meanDiff := mean(diffs)
+	var sumSq float64
+	for _, d := range diffs {
+		sumSq += (d - meanDiff) * (d - meanDiff)
+	}
+	variance := sumSq / float64(n-1)
+	stderr := math.Sqrt(variance / float64(n))
+	df = float64(n - 1)
+	if stderr == 0 {
+		return 0, df, 1, nil
+	}
+	t = meanDiff / stderr
+	p = 2 * (1 - studentTCDF(math.Abs(t), df))
+	return t, df, p, nil
+}
+
+// mannWhitneyU runs a two-sided Mann-Whitney U test (normal approximation,
+// no continuity correction) comparing unpaired samples a and b.
+func mannWhitneyU(a, b []float64) (u, z, p float64, err error) {
+	if len(a) == 0 || len(b) == 0 {
+		return 0, 0, 0, fmt.Errorf("need at least 1 sample per group")
+	}
+	type rankedSample struct {
+		value float64
+		group int
+	}
+	all := make([]rankedSample, 0, len(a)+len(b))
+	for _, v := range a {
+		all = append(all, rankedSample{v, 0})
+	}
+	for _, v := range b {
+		all = append(all, rankedSample{v, 1})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].value < all[j].value })
+
+	ranks := make([]float64, len(all))
+	for i := 0; i < len(all); {
+		j := i
+		for j+1 < len(all) && all[j+1].value == all[i].value {
+			j++
+		}
+		avgRank := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			ranks[k] = avgRank
+		}
+		i = j + 1
+	}
+
+	var rankSumA float64
+	for idx, s := range all {
+		if s.group == 0 {
+			rankSumA += ranks[idx]
+		}
+	}
+
+	n1, n2 := float64(len(a)), float64(len(b))
+	uA := rankSumA - n1*(n1+1)/2
+	uB := n1*n2 - uA
+	u = math.Min(uA, uB)
+
+	meanU := n1 * n2 / 2
+	stdU := math.Sqrt(n1 * n2 * (n1 + n2 + 1) / 12)
+	if stdU == 0 {
+		return u, 0, 1, nil
+	}
+	z = (u - meanU) / stdU
+	p = 2 * (1 - normalCDF(math.Abs(z)))
+	return u, z, p, nil
+}
+
+// cmdAnalyze implements "bench analyze": a paired t-test and a Mann-Whitney
+// U test between two configurations' per-concurrency QPS samples, to say
+// whether the difference between them is statistically significant.
+func cmdAnalyze(args []string) {
+	fs := flag.NewFlagSet("bench analyze", flag.ExitOnError)
+	inputA := fs.String("a", "", "Comma-separated tidb_test_comparison_*.json files for configuration A (required)")
+	inputB := fs.String("b", "", "Comma-separated tidb_test_comparison_*.json files for configuration B (required)")
+	splitA := fs.Bool("split-a", false, "SplitRegions value selecting configuration A within each -a file")
+	futureTSA := fs.Bool("future-ts-a", false, "UseFutureTS value selecting configuration A within each -a file")
+	splitB := fs.Bool("split-b", true, "SplitRegions value selecting configuration B within each -b file")
+	futureTSB := fs.Bool("future-ts-b", true, "UseFutureTS value selecting configuration B within each -b file")
+	fs.Parse(args)
+
+	if *inputA == "" || *inputB == "" {
+		log.Fatalf("-a and -b are both required")
+	}
+
+	runnersA, err := loadComparisonFiles(*inputA)
+	if err != nil {
+		log.Fatalf("Failed to load -a: %v", err)
+	}
+	runnersB, err := loadComparisonFiles(*inputB)
+	if err != nil {
+		log.Fatalf("Failed to load -b: %v", err)
+	}
+
+	samplesA := collectQPSSamples(runnersA, *splitA, *futureTSA)
+	samplesB := collectQPSSamples(runnersB, *splitB, *futureTSB)
+
+	var levels []int
+	for level := range samplesA {
+		if _, ok := samplesB[level]; ok {
+			levels = append(levels, level)
+		}
+	}
+	sort.Ints(levels)
+	if len(levels) == 0 {
+		log.Fatalf("No matching concurrency levels found between -a and -b")
+	}
+
+	fmt.Println("\n=== Statistical Comparison: A vs B (QPS) ===")
+	fmt.Printf("%-10s %-10s %-10s %-10s %-8s %-10s %-8s %-10s\n",
+		"Concurr.", "Mean A", "Mean B", "Delta %", "t", "p(t-test)", "U", "p(MW-U)")
+
+	for _, level := range levels {
+		a := samplesA[level]
+		b := samplesB[level]
+
+		meanA := mean(a)
+		meanB := mean(b)
+		var deltaPct float64
+		if meanA != 0 {
+			deltaPct = (meanB - meanA) / meanA * 100
+		}
+
+		tStat, pT := math.NaN(), math.NaN()
+		if len(a) == len(b) {
+			if t, _, p, err := pairedTTest(a, b); err == nil {
+				tStat, pT = t, p
+			}
+		}
+
+		u, pU := math.NaN(), math.NaN()
+		if stat, _, p, err := mannWhitneyU(a, b); err == nil {
+			u, pU = stat, p
+		}
+
+		fmt.Printf("%-10d %-10.2f %-10.2f %-10.2f %-8.4f %-10.4f %-8.2f %-10.4f\n",
+			level, meanA, meanB, deltaPct, tStat, pT, u, pU)
+	}
+
+	fmt.Println("\nNote: p < 0.05 is conventionally treated as a statistically significant difference.")
+}
+
+func printUsage() {
+	fmt.Println("usage: tidb_future_ts_benchmark <command> [flags]")
+	fmt.Println("\nCommands:")
+	fmt.Println("  run      Run a single configuration, or the legacy split x future-ts 2x2, against one cluster")
+	fmt.Println("  sweep    Run every configuration in a YAML test plan's matrix against one cluster")
+	fmt.Println("  report   Regenerate a comparison report from one or more saved tidb_test_comparison_*.json files")
+	fmt.Println("  analyze  Run a paired t-test / Mann-Whitney U test between two configurations' QPS samples")
+	fmt.Println("\nRun `tidb_future_ts_benchmark <command> -h` for command-specific flags.")
+}
+
+// main dispatches to the run/sweep/report/analyze subcommands by hand
+// rather than through kingpin or cobra: this tool has no go.mod, and beyond
+// the Prometheus/MySQL clients and yaml.v3 (for test plan files) already
+// needed elsewhere, a flag.FlagSet per subcommand (registerCommonFlags plus
+// each cmd*'s own flags) gets the same "<tool> <verb> -flags" UX without
+// pulling in a CLI-framework dependency just for argument parsing.
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(1)
+	}
+
+	cmd := os.Args[1]
+	args := os.Args[2:]
+	switch cmd {
+	case "run":
+		cmdRun(args)
+	case "sweep":
+		cmdSweep(args)
+	case "report":
+		cmdReport(args)
+	case "analyze":
+		cmdAnalyze(args)
+	case "-h", "--help", "help":
+		printUsage()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q; expected run, sweep, report, or analyze\n\n", cmd)
+		printUsage()
+		os.Exit(1)
 	}
 }